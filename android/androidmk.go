@@ -7,8 +7,10 @@ import (
 	"io/ioutil"
 	"os"
 	"path/filepath"
+	"runtime"
 	"sort"
 	"strings"
+	"sync"
 
 	"github.com/google/blueprint"
 )
@@ -36,6 +38,23 @@ type AndroidMkData struct {
 
 type AndroidMkExtraFunc func(w io.Writer, outputFile Path)
 
+func androidMkDataForModule(mod Module) (AndroidMkData, bool) {
+	provider, ok := mod.(AndroidMkDataProvider)
+	if !ok {
+		return AndroidMkData{}, false
+	}
+
+	base := mod.base()
+	if base.androidMkData == nil {
+		data := provider.AndroidMk()
+		base.androidMkData = &data
+	}
+
+	data := *base.androidMkData
+	data.Required = append([]string(nil), data.Required...)
+	return data, true
+}
+
 func AndroidMkSingleton() Singleton {
 	return &androidMkSingleton{}
 }
@@ -55,31 +74,93 @@ func (c *androidMkSingleton) GenerateBuildActions(ctx SingletonContext) {
 
 	sort.Sort(AndroidModulesByName{androidMkModulesList, ctx})
 
-	transMk := PathForOutput(ctx, "Android"+String(ctx.Config().productVariables.Make_suffix)+".mk")
+	suffix := String(ctx.Config().productVariables.Make_suffix)
+	transMk := PathForOutput(ctx, "Android"+suffix+".mk")
+	shardDir := PathForOutput(ctx, "Android"+suffix)
 	if ctx.Failed() {
 		return
 	}
 
-	err := translateAndroidMk(ctx, transMk.String(), androidMkModulesList)
+	shards, err := translateAndroidMk(ctx, transMk.String(), shardDir.String(), androidMkModulesList)
 	if err != nil {
 		ctx.Errorf(err.Error())
+		return
+	}
+
+	outputs := WritablePaths{transMk}
+	for _, shard := range shards {
+		outputs = append(outputs, PathForOutput(ctx, "Android"+suffix, shard))
 	}
 
 	ctx.Build(pctx, BuildParams{
-		Rule:   blueprint.Phony,
-		Output: transMk,
+		Rule:    blueprint.Phony,
+		Outputs: outputs,
 	})
 }
 
-func translateAndroidMk(ctx SingletonContext, mkFile string, mods []Module) error {
-	buf := &bytes.Buffer{}
-	fmt.Fprintln(buf, "LOCAL_MODULE_MAKEFILE := $(lastword $(MAKEFILE_LIST))")
+func androidMkShardName(moduleDir string) string {
+	top := strings.SplitN(filepath.ToSlash(moduleDir), "/", 2)[0]
+	if top == "" || top == "." {
+		top = "_root"
+	}
+	return top + ".mk"
+}
+
+type androidMkEntry struct {
+	name      string
+	prefix    string
+	moduleDir string
+	data      AndroidMkData
+}
+
+func (e *androidMkEntry) write(w io.Writer) {
+	if e.data.Custom != nil {
+		e.data.Custom(w, e.name, e.prefix, e.moduleDir, e.data)
+	} else {
+		WriteAndroidMkData(w, e.data)
+	}
+}
+
+func translateAndroidMk(ctx SingletonContext, mkFile, shardDir string, mods []Module) ([]string, error) {
+	entries := make([]*androidMkEntry, len(mods))
+	for i, mod := range mods {
+		entries[i] = androidMkEntryForModule(ctx, mod)
+	}
+
+	rendered := make([][]byte, len(mods))
+	jobs := make(chan int)
+	wg := sync.WaitGroup{}
+	for i := 0; i < runtime.NumCPU(); i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for j := range jobs {
+				buf := &bytes.Buffer{}
+				entries[j].write(buf)
+				rendered[j] = buf.Bytes()
+			}
+		}()
+	}
+	for i, entry := range entries {
+		if entry != nil {
+			jobs <- i
+		}
+	}
+	close(jobs)
+	wg.Wait()
+
+	shardBufs := make(map[string]*bytes.Buffer)
 	type_stats := make(map[string]int)
-	for _, mod := range mods {
-		err := translateAndroidMkModule(ctx, buf, mod)
-		if err != nil {
-			os.Remove(mkFile)
-			return err
+	for i, mod := range mods {
+		if len(rendered[i]) > 0 {
+			shard := androidMkShardName(ctx.ModuleDir(mod))
+			buf := shardBufs[shard]
+			if buf == nil {
+				buf = &bytes.Buffer{}
+				fmt.Fprintln(buf, "LOCAL_MODULE_MAKEFILE := $(lastword $(MAKEFILE_LIST))")
+				shardBufs[shard] = buf
+			}
+			buf.Write(rendered[i])
 		}
 
 		if ctx.PrimaryModule(mod) == mod {
@@ -87,6 +168,35 @@ func translateAndroidMk(ctx SingletonContext, mkFile string, mods []Module) erro
 		}
 	}
 
+	var shards []string
+	for shard := range shardBufs {
+		shards = append(shards, shard)
+	}
+	sort.Strings(shards)
+
+	if err := os.MkdirAll(shardDir, 0777); err != nil {
+		return nil, err
+	}
+
+	for _, shard := range shards {
+		if err := writeFileIfChanged(filepath.Join(shardDir, shard), shardBufs[shard].Bytes()); err != nil {
+			return nil, err
+		}
+	}
+
+	if existing, err := ioutil.ReadDir(shardDir); err == nil {
+		for _, info := range existing {
+			if strings.HasSuffix(info.Name(), ".mk") && !InList(info.Name(), shards) {
+				os.Remove(filepath.Join(shardDir, info.Name()))
+			}
+		}
+	}
+
+	buf := &bytes.Buffer{}
+	for _, shard := range shards {
+		fmt.Fprintln(buf, "include", filepath.Join(shardDir, shard))
+	}
+
 	keys := []string{}
 	fmt.Fprintln(buf, "\nSTATS.SOONG_MODULE_TYPE :=")
 	for k := range type_stats {
@@ -98,28 +208,10 @@ func translateAndroidMk(ctx SingletonContext, mkFile string, mods []Module) erro
 		fmt.Fprintf(buf, "STATS.SOONG_MODULE_TYPE.%s := %d\n", mod_type, type_stats[mod_type])
 	}
 
-	if _, err := os.Stat(mkFile); !os.IsNotExist(err) {
-		if data, err := ioutil.ReadFile(mkFile); err == nil {
-			matches := buf.Len() == len(data)
-			if matches {
-				for i, value := range buf.Bytes() {
-					if value != data[i] {
-						matches = false
-						break
-					}
-				}
-			}
-
-			if matches {
-				return nil
-			}
-		}
-	}
-
-	return ioutil.WriteFile(mkFile, buf.Bytes(), 0666)
+	return shards, writeFileIfChanged(mkFile, buf.Bytes())
 }
 
-func translateAndroidMkModule(ctx SingletonContext, w io.Writer, mod blueprint.Module) error {
+func androidMkEntryForModule(ctx SingletonContext, mod blueprint.Module) *androidMkEntry {
 	provider, ok := mod.(AndroidMkDataProvider)
 	if !ok {
 		return nil
@@ -140,7 +232,7 @@ func translateAndroidMkModule(ctx SingletonContext, w io.Writer, mod blueprint.M
 		return nil
 	}
 
-	data := provider.AndroidMk()
+	data, _ := androidMkDataForModule(mod.(Module))
 
 	if data.Include == "" {
 		data.Include = "$(BUILD_PREBUILT)"
@@ -164,7 +256,11 @@ func translateAndroidMkModule(ctx SingletonContext, w io.Writer, mod blueprint.M
 
 		}
 
-		if amod.Arch().ArchType != ctx.Config().Targets[amod.Os().Class][0].Arch.ArchType {
+		targets := ctx.Config().Targets[amod.Os().Class]
+		if amod.Target().NativeBridge {
+			targets = ctx.Config().NativeBridgeTargets
+		}
+		if amod.Arch().ArchType != targets[0].Arch.ArchType {
 			prefix = "2ND_" + prefix
 		}
 	}
@@ -209,20 +305,32 @@ func translateAndroidMkModule(ctx SingletonContext, w io.Writer, mod blueprint.M
 		if Bool(amod.commonProperties.Vendor) || Bool(amod.commonProperties.Soc_specific) {
 			fmt.Fprintln(&data.preamble, "LOCAL_VENDOR_MODULE := true")
 		}
-		if Bool(amod.commonProperties.Device_specific) {
+		if Bool(amod.commonProperties.Device_specific) || Bool(amod.commonProperties.Odm_specific) {
 			fmt.Fprintln(&data.preamble, "LOCAL_ODM_MODULE := true")
 		}
 		if Bool(amod.commonProperties.Product_specific) {
 			fmt.Fprintln(&data.preamble, "LOCAL_PRODUCT_MODULE := true")
 		}
+		if Bool(amod.commonProperties.System_ext_specific) {
+			fmt.Fprintln(&data.preamble, "LOCAL_SYSTEM_EXT_MODULE := true")
+		}
 		if amod.commonProperties.Owner != nil {
 			fmt.Fprintln(&data.preamble, "LOCAL_MODULE_OWNER :=", *amod.commonProperties.Owner)
 		}
 		if amod.commonProperties.Notice != nil {
 			fmt.Fprintln(&data.preamble, "LOCAL_NOTICE_FILE :=", "$(LOCAL_PATH)/"+*amod.commonProperties.Notice)
+		} else if texts := amod.LicenseTexts(); len(texts) > 0 {
+			fmt.Fprintln(&data.preamble, "LOCAL_NOTICE_FILE :=", strings.Join(texts.Strings(), " "))
 		}
 	}
 
+	if kinds := amod.LicenseKinds(); len(kinds) > 0 {
+		fmt.Fprintln(&data.preamble, "LOCAL_LICENSE_KINDS :=", strings.Join(kinds, " "))
+	}
+	if conditions := amod.LicenseConditions(); len(conditions) > 0 {
+		fmt.Fprintln(&data.preamble, "LOCAL_LICENSE_CONDITIONS :=", strings.Join(conditions, " "))
+	}
+
 	if host {
 		makeOs := amod.Os().String()
 		if amod.Os() == Linux || amod.Os() == LinuxBionic {
@@ -232,15 +340,12 @@ func translateAndroidMkModule(ctx SingletonContext, w io.Writer, mod blueprint.M
 		fmt.Fprintln(&data.preamble, "LOCAL_IS_HOST_MODULE := true")
 	}
 
-	blueprintDir := filepath.Dir(ctx.BlueprintFile(mod))
-
-	if data.Custom != nil {
-		data.Custom(w, name, prefix, blueprintDir, data)
-	} else {
-		WriteAndroidMkData(w, data)
+	return &androidMkEntry{
+		name:      name,
+		prefix:    prefix,
+		moduleDir: filepath.Dir(ctx.BlueprintFile(mod)),
+		data:      data,
 	}
-
-	return nil
 }
 
 func WriteAndroidMkData(w io.Writer, data AndroidMkData) {
@@ -12,12 +12,13 @@ import (
 var (
 	archTypeList []ArchType
 
-	Arm    = newArch("arm", "lib32")
-	Arm64  = newArch("arm64", "lib64")
-	Mips   = newArch("mips", "lib32")
-	Mips64 = newArch("mips64", "lib64")
-	X86    = newArch("x86", "lib32")
-	X86_64 = newArch("x86_64", "lib64")
+	Arm     = newArch("arm", "lib32")
+	Arm64   = newArch("arm64", "lib64")
+	Mips    = newArch("mips", "lib32")
+	Mips64  = newArch("mips64", "lib64")
+	X86     = newArch("x86", "lib32")
+	X86_64  = newArch("x86_64", "lib64")
+	Riscv64 = newArch("riscv64", "lib64")
 
 	Common = ArchType{
 		Name: "common",
@@ -25,12 +26,13 @@ var (
 )
 
 var archTypeMap = map[string]ArchType{
-	"arm":    Arm,
-	"arm64":  Arm64,
-	"mips":   Mips,
-	"mips64": Mips64,
-	"x86":    X86,
-	"x86_64": X86_64,
+	"arm":     Arm,
+	"arm64":   Arm64,
+	"mips":    Mips,
+	"mips64":  Mips64,
+	"x86":     X86,
+	"x86_64":  X86_64,
+	"riscv64": Riscv64,
 }
 
 /*
@@ -51,6 +53,8 @@ module {
                     },
         x86_64: {
                     },
+        riscv64: {
+                    },
     },
     multilib: {
         lib32: {
@@ -173,10 +177,10 @@ var (
 
 	osArchTypeMap = map[OsType][]ArchType{
 		Linux:       []ArchType{Arm, Arm64, X86, X86_64},
-		LinuxBionic: []ArchType{X86_64},
+		LinuxBionic: []ArchType{Arm64, X86_64},
 		Darwin:      []ArchType{X86, X86_64},
 		Windows:     []ArchType{X86, X86_64},
-		Android:     []ArchType{Arm, Arm64, Mips, Mips64, X86, X86_64},
+		Android:     []ArchType{Arm, Arm64, Mips, Mips64, X86, X86_64, Riscv64},
 	}
 )
 
@@ -255,10 +259,17 @@ func osByName(name string) OsType {
 type Target struct {
 	Os   OsType
 	Arch Arch
+
+	NativeBridge             bool
+	NativeBridgeRelativePath string
 }
 
 func (target Target) String() string {
-	return target.Os.String() + "_" + target.Arch.String()
+	s := target.Os.String() + "_" + target.Arch.String()
+	if target.NativeBridge {
+		s += "_native_bridge"
+	}
+	return s
 }
 
 func archMutator(mctx BottomUpMutatorContext) {
@@ -310,6 +321,15 @@ func archMutator(mctx BottomUpMutatorContext) {
 			primaryModules[len(moduleTargets)] = true
 			moduleTargets = append(moduleTargets, targets...)
 		}
+
+		if class == Device && Bool(module.base().commonProperties.Native_bridge_supported) &&
+			!strings.HasPrefix(multilib, "common") {
+			nativeBridgeTargets, err := decodeMultilib(multilib, mctx.Config().NativeBridgeTargets, prefer32)
+			if err != nil {
+				mctx.ModuleErrorf("%s", err.Error())
+			}
+			moduleTargets = append(moduleTargets, nativeBridgeTargets...)
+		}
 	}
 
 	if len(moduleTargets) == 0 {
@@ -735,7 +755,12 @@ func decodeTargetProductVariables(config *config) (map[OsClass][]Target, error)
 	}
 
 	if Bool(config.Host_bionic) {
-		addTarget(LinuxBionic, "x86_64", nil, nil, nil)
+		switch *variables.HostArch {
+		case "arm64":
+			addTarget(LinuxBionic, "arm64", nil, nil, nil)
+		default:
+			addTarget(LinuxBionic, "x86_64", nil, nil, nil)
+		}
 	}
 
 	if String(variables.CrossHost) != "" {
@@ -777,6 +802,55 @@ func decodeTargetProductVariables(config *config) (map[OsClass][]Target, error)
 	return targets, nil
 }
 
+func decodeNativeBridgeTargets(config *config) ([]Target, error) {
+	variables := config.productVariables
+
+	var targets []Target
+	addTarget := func(archName string, archVariant, cpuVariant *string, abi *[]string) error {
+		arch, err := decodeArch(archName, archVariant, cpuVariant, abi)
+		if err != nil {
+			return err
+		}
+		arch.Native = true
+		targets = append(targets, Target{
+			Os:                       Android,
+			Arch:                     arch,
+			NativeBridge:             true,
+			NativeBridgeRelativePath: arch.ArchType.Name,
+		})
+		return nil
+	}
+
+	if String(variables.DeviceNativeBridgeArch) == "" {
+		if String(variables.DeviceNativeBridgeSecondaryArch) != "" {
+			return nil, fmt.Errorf("DeviceNativeBridgeSecondaryArch requires DeviceNativeBridgeArch")
+		}
+		return nil, nil
+	}
+
+	if String(variables.DeviceArch) == "" {
+		return nil, fmt.Errorf("DeviceNativeBridgeArch requires DeviceArch")
+	}
+
+	err := addTarget(*variables.DeviceNativeBridgeArch, variables.DeviceNativeBridgeArchVariant,
+		variables.DeviceNativeBridgeCpuVariant, variables.DeviceNativeBridgeAbi)
+	if err != nil {
+		return nil, err
+	}
+
+	if String(variables.DeviceNativeBridgeSecondaryArch) != "" {
+		err := addTarget(*variables.DeviceNativeBridgeSecondaryArch,
+			variables.DeviceNativeBridgeSecondaryArchVariant,
+			variables.DeviceNativeBridgeSecondaryCpuVariant,
+			variables.DeviceNativeBridgeSecondaryAbi)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	return targets, nil
+}
+
 func hasArmAbi(arch Arch) bool {
 	for _, abi := range arch.Abi {
 		if strings.HasPrefix(abi, "arm") {
@@ -860,6 +934,7 @@ func getMegaDeviceConfig() []archConfig {
 		{"x86_64", "ivybridge", "", []string{"x86_64"}},
 		{"x86_64", "sandybridge", "", []string{"x86_64"}},
 		{"x86_64", "silvermont", "", []string{"x86_64"}},
+		{"riscv64", "", "", []string{"riscv64"}},
 	}
 }
 
@@ -0,0 +1,202 @@
+package android
+
+import (
+	"bytes"
+	"fmt"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"github.com/google/blueprint"
+)
+
+func BuildGraphLintSingleton() Singleton {
+	return &buildGraphLintSingleton{}
+}
+
+type buildGraphLintSingleton struct{}
+
+type buildGraphLintProblem struct {
+	kind    string
+	owner   string
+	path    string
+	message string
+}
+
+func (c *config) captureSingletonBuild(params BuildParams) {
+	c.singletonBuildParamsLock.Lock()
+	defer c.singletonBuildParamsLock.Unlock()
+	c.singletonBuildParams = append(c.singletonBuildParams, params)
+}
+
+func buildParamsOutputs(params BuildParams) WritablePaths {
+	var outputs WritablePaths
+	if params.Output != nil {
+		outputs = append(outputs, params.Output)
+	}
+	outputs = append(outputs, params.Outputs...)
+	if params.ImplicitOutput != nil {
+		outputs = append(outputs, params.ImplicitOutput)
+	}
+	outputs = append(outputs, params.ImplicitOutputs...)
+	return outputs
+}
+
+func buildParamsInputs(params BuildParams) Paths {
+	var inputs Paths
+	if params.Input != nil {
+		inputs = append(inputs, params.Input)
+	}
+	inputs = append(inputs, params.Inputs...)
+	if params.Implicit != nil {
+		inputs = append(inputs, params.Implicit)
+	}
+	inputs = append(inputs, params.Implicits...)
+	inputs = append(inputs, params.OrderOnly...)
+	return inputs
+}
+
+func hasPathPrefix(path, prefix string) bool {
+	return path == prefix || strings.HasPrefix(path, prefix+"/")
+}
+
+func (s *buildGraphLintSingleton) GenerateBuildActions(ctx SingletonContext) {
+	mode := ctx.Config().buildGraphLint
+	if mode == "" {
+		return
+	}
+
+	productOut := PathForOutput(ctx, "target", "product", ctx.Config().DeviceName()).String()
+	hostOut := PathForOutput(ctx, "host").String()
+	debugOut := PathForOutput(ctx, "debug").String()
+
+	var problems []buildGraphLintProblem
+	owners := make(map[string]string)
+	type pendingInput struct {
+		owner string
+		path  string
+	}
+	var inputs []pendingInput
+
+	declare := func(owner string, output WritablePath) {
+		path := output.String()
+		if prev, exists := owners[path]; exists && prev != owner {
+			problems = append(problems, buildGraphLintProblem{
+				kind:    "duplicate-output",
+				owner:   owner,
+				path:    path,
+				message: fmt.Sprintf("output is also declared by %s", prev),
+			})
+			return
+		}
+		owners[path] = owner
+	}
+
+	ctx.VisitAllModules(func(module Module) {
+		base := module.base()
+		owner := moduleGraphId(ctx, module)
+
+		moduleOut := PathForOutput(ctx, ".intermediates", ctx.ModuleDir(module),
+			ctx.ModuleName(module), ctx.ModuleSubDir(module)).String()
+		installOut := productOut
+		if base.Target().Os.Class != Device {
+			installOut = hostOut
+		}
+		roots := []string{
+			moduleOut,
+			installOut,
+			filepath.Join(debugOut, strings.TrimPrefix(installOut, ctx.Config().BuildDir()+"/")),
+		}
+
+		for _, params := range base.buildParams {
+			for _, output := range buildParamsOutputs(params) {
+				switch output.(type) {
+				case PhonyPath, DistPath:
+					declare(owner, output)
+					continue
+				}
+
+				path := output.String()
+				inRoot := false
+				for _, root := range roots {
+					if hasPathPrefix(path, root) {
+						inRoot = true
+						break
+					}
+				}
+				if !inRoot {
+					problems = append(problems, buildGraphLintProblem{
+						kind:    "output-outside-module",
+						owner:   owner,
+						path:    path,
+						message: fmt.Sprintf("output is not under %s or the install directory", moduleOut),
+					})
+				}
+				declare(owner, output)
+			}
+
+			for _, input := range buildParamsInputs(params) {
+				if _, ok := input.(WritablePath); ok {
+					inputs = append(inputs, pendingInput{owner: owner, path: input.String()})
+				}
+			}
+		}
+	})
+
+	ctx.Config().singletonBuildParamsLock.Lock()
+	singletonParams := ctx.Config().singletonBuildParams
+	ctx.Config().singletonBuildParamsLock.Unlock()
+	for _, params := range singletonParams {
+		for _, output := range buildParamsOutputs(params) {
+			declare("<singleton>", output)
+		}
+	}
+
+	// Host tools built by blueprint bootstrap don't come from any android module or singleton.
+	ctx.VisitAllModulesBlueprint(func(module blueprint.Module) {
+		if _, ok := module.(Module); ok {
+			return
+		}
+		if tool, ok := module.(BootstrapHostTool); ok {
+			if path, err := PathForBootstrapHostTool(ctx, tool); err == nil {
+				declare("<bootstrap>", path)
+			}
+		}
+	})
+
+	for _, input := range inputs {
+		if _, produced := owners[input.path]; !produced {
+			problems = append(problems, buildGraphLintProblem{
+				kind:    "unproduced-input",
+				owner:   input.owner,
+				path:    input.path,
+				message: "input is in the output directory but no rule produces it",
+			})
+		}
+	}
+
+	sort.Slice(problems, func(i, j int) bool {
+		if problems[i].kind != problems[j].kind {
+			return problems[i].kind < problems[j].kind
+		}
+		if problems[i].owner != problems[j].owner {
+			return problems[i].owner < problems[j].owner
+		}
+		return problems[i].path < problems[j].path
+	})
+
+	buf := &bytes.Buffer{}
+	for _, p := range problems {
+		fmt.Fprintf(buf, "%s: %s: %s: %s\n", p.kind, p.owner, p.path, p.message)
+	}
+
+	report := PathForOutput(ctx, "build_graph_lint.txt").String()
+	if err := writeFileIfChanged(report, buf.Bytes()); err != nil {
+		ctx.Errorf("%s", err.Error())
+		return
+	}
+
+	if mode == "error" && len(problems) > 0 {
+		ctx.Errorf("build graph lint found %d problem(s), see %s", len(problems), report)
+	}
+}
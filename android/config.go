@@ -7,6 +7,7 @@ import (
 	"os"
 	"path/filepath"
 	"runtime"
+	"sort"
 	"strconv"
 	"strings"
 	"sync"
@@ -34,6 +35,8 @@ func (f *FileConfigurableOptions) SetDefaultConfig() {
 
 type Config struct {
 	*config
+
+	envReader string
 }
 
 func (c Config) BuildDir() string {
@@ -58,6 +61,7 @@ type config struct {
 	ConfigFileName           string
 	ProductVariablesFileName string
 	Targets                  map[OsClass][]Target
+	NativeBridgeTargets      []Target
 	BuildOsVariant           string
 	deviceConfig             *deviceConfig
 	srcDir                   string
@@ -65,6 +69,7 @@ type config struct {
 	env                      map[string]string
 	envLock                  sync.Mutex
 	envDeps                  map[string]string
+	envReaders               map[string]map[string]bool
 	envFrozen                bool
 	inMake                   bool
 	captureBuild             bool
@@ -72,6 +77,13 @@ type config struct {
 	useOpenJDK9              bool
 	targetOpenJDK9           bool
 	stopBefore               bootstrap.StopBefore
+	hostTools                *HostToolResolver
+	neverallowRules          []*rule
+	neverallowFiles          []string
+	buildGraphLint           string
+	singletonBuildParams     []BuildParams
+	singletonBuildParamsLock sync.Mutex
+	metricsPhases            sync.Map
 	OncePer
 }
 
@@ -107,8 +119,11 @@ func loadFromConfigFile(configurable jsonConfigurable, filename string) error {
 	} else if err != nil {
 		return fmt.Errorf("config file: could not open %s: %s", filename, err.Error())
 	} else {
-		jsonDecoder := json.NewDecoder(configFileReader)
-		err = jsonDecoder.Decode(configurable)
+		data, err := ioutil.ReadAll(configFileReader)
+		if err != nil {
+			return fmt.Errorf("config file: could not read %s: %s", filename, err.Error())
+		}
+		err = decodeJsonConfigStrict(data, configurable)
 		if err != nil {
 			return fmt.Errorf("config file: %s did not parse correctly: %s", filename, err.Error())
 		}
@@ -160,6 +175,7 @@ func TestConfig(buildDir string, env map[string]string) Config {
 		buildDir:     buildDir,
 		captureBuild: true,
 		env:          env,
+		hostTools:    NewHostToolResolver("", nil, nil),
 	}
 	config.deviceConfig = &deviceConfig{
 		config: config,
@@ -170,7 +186,7 @@ func TestConfig(buildDir string, env map[string]string) Config {
 		panic(err)
 	}
 
-	return Config{config}
+	return Config{config: config}
 }
 
 func TestArchConfig(buildDir string, env map[string]string) Config {
@@ -179,14 +195,15 @@ func TestArchConfig(buildDir string, env map[string]string) Config {
 
 	config.Targets = map[OsClass][]Target{
 		Device: []Target{
-			{Android, Arch{ArchType: Arm64, ArchVariant: "armv8-a", Native: true}},
-			{Android, Arch{ArchType: Arm, ArchVariant: "armv7-a-neon", Native: true}},
+			{Os: Android, Arch: Arch{ArchType: Arm64, ArchVariant: "armv8-a", Native: true}},
+			{Os: Android, Arch: Arch{ArchType: Arm, ArchVariant: "armv7-a-neon", Native: true}},
 		},
 		Host: []Target{
-			{BuildOs, Arch{ArchType: X86_64}},
-			{BuildOs, Arch{ArchType: X86}},
+			{Os: BuildOs, Arch: Arch{ArchType: X86_64}},
+			{Os: BuildOs, Arch: Arch{ArchType: X86}},
 		},
 	}
+	config.BuildOsVariant = config.Targets[Host][0].String()
 
 	return testConfig
 }
@@ -223,6 +240,16 @@ func NewConfig(srcDir, buildDir string) (Config, error) {
 		return Config{}, err
 	}
 
+	err = config.initHostTools()
+	if err != nil {
+		return Config{}, err
+	}
+
+	err = loadNeverallowRules(config)
+	if err != nil {
+		return Config{}, err
+	}
+
 	inMakeFile := filepath.Join(buildDir, ".soong.in_make")
 	if _, err := os.Stat(inMakeFile); err == nil {
 		config.inMake = true
@@ -251,11 +278,16 @@ func NewConfig(srcDir, buildDir string) (Config, error) {
 	config.Targets = targets
 	config.BuildOsVariant = targets[Host][0].String()
 
+	config.NativeBridgeTargets, err = decodeNativeBridgeTargets(config)
+	if err != nil {
+		return Config{}, err
+	}
+
 	if err := config.fromEnv(); err != nil {
 		return Config{}, err
 	}
 
-	return Config{config}, nil
+	return Config{config: config}, nil
 }
 
 func (c *config) fromEnv() error {
@@ -275,6 +307,44 @@ func (c *config) fromEnv() error {
 		return fmt.Errorf(`Invalid value for EXPERIMENTAL_USE_OPENJDK9, should be "", "false", "1.8", or "true"`)
 	}
 
+	switch lint := c.Getenv("SOONG_BUILD_GRAPH_LINT"); lint {
+	case "":
+	case "report", "error":
+		c.buildGraphLint = lint
+		c.captureBuild = true
+	default:
+		return fmt.Errorf(`Invalid value for SOONG_BUILD_GRAPH_LINT, should be "", "report", or "error"`)
+	}
+
+	return nil
+}
+
+func (c *config) initHostTools() error {
+	prefix := String(c.productVariables.HostToolsPrefix)
+	if prefix == "" {
+		prefix = c.Getenv("SOONG_HOST_TOOLS_PREFIX")
+	}
+
+	var searchPath []string
+	if Bool(c.productVariables.HostToolsUsePath) || c.IsEnvTrue("SOONG_HOST_TOOLS_USE_PATH") {
+		searchPath = filepath.SplitList(c.Getenv("PATH"))
+	}
+
+	toolMapFile := String(c.productVariables.HostToolsMap)
+	if toolMapFile == "" {
+		toolMapFile = c.Getenv("SOONG_HOST_TOOLS_MAP")
+	}
+
+	var toolMap map[string]HostToolSpec
+	if toolMapFile != "" {
+		var err error
+		toolMap, err = LoadHostToolMap(toolMapFile)
+		if err != nil {
+			return err
+		}
+	}
+
+	c.hostTools = NewHostToolResolver(prefix, searchPath, toolMap)
 	return nil
 }
 
@@ -306,6 +376,18 @@ func (c *config) HostSystemTool(name string) string {
 	return name
 }
 
+func (c *config) HostToolsPrefix() string {
+	return c.hostTools.Prefix()
+}
+
+func (c *config) HostTool(name string) string {
+	return c.hostTools.Resolve(name)
+}
+
+func (c *config) SkipHostToolsCheck() bool {
+	return Bool(c.productVariables.HostToolsSkipCheck)
+}
+
 func (c *config) PrebuiltOS() string {
 	switch runtime.GOOS {
 	case "android", "linux":
@@ -332,13 +414,18 @@ func (c *config) CpPreserveSymlinksFlags() string {
 	}
 }
 
-func (c *config) Getenv(key string) string {
+func (c Config) withEnvReader(reader string) Config {
+	return Config{config: c.config, envReader: reader}
+}
+
+func (c *config) getenv(reader, key string) string {
 	var val string
 	var exists bool
 	c.envLock.Lock()
 	defer c.envLock.Unlock()
 	if c.envDeps == nil {
 		c.envDeps = make(map[string]string)
+		c.envReaders = make(map[string]map[string]bool)
 	}
 	if val, exists = c.envDeps[key]; !exists {
 		if c.envFrozen {
@@ -347,24 +434,60 @@ func (c *config) Getenv(key string) string {
 		val, _ = c.env[key]
 		c.envDeps[key] = val
 	}
+	if reader == "" {
+		reader = "soong_build"
+	}
+	if c.envReaders[key] == nil {
+		c.envReaders[key] = make(map[string]bool)
+	}
+	c.envReaders[key][reader] = true
 	return val
 }
 
+func (c *config) Getenv(key string) string {
+	return c.getenv("", key)
+}
+
 func (c *config) GetenvWithDefault(key string, defaultValue string) string {
-	ret := c.Getenv(key)
-	if ret == "" {
+	return envWithDefault(c.Getenv(key), defaultValue)
+}
+
+func (c *config) IsEnvTrue(key string) bool {
+	return envTrue(c.Getenv(key))
+}
+
+func (c *config) IsEnvFalse(key string) bool {
+	return envFalse(c.Getenv(key))
+}
+
+func (c Config) Getenv(key string) string {
+	return c.config.getenv(c.envReader, key)
+}
+
+func (c Config) GetenvWithDefault(key string, defaultValue string) string {
+	return envWithDefault(c.Getenv(key), defaultValue)
+}
+
+func (c Config) IsEnvTrue(key string) bool {
+	return envTrue(c.Getenv(key))
+}
+
+func (c Config) IsEnvFalse(key string) bool {
+	return envFalse(c.Getenv(key))
+}
+
+func envWithDefault(value, defaultValue string) string {
+	if value == "" {
 		return defaultValue
 	}
-	return ret
+	return value
 }
 
-func (c *config) IsEnvTrue(key string) bool {
-	value := c.Getenv(key)
+func envTrue(value string) bool {
 	return value == "1" || value == "y" || value == "yes" || value == "on" || value == "true"
 }
 
-func (c *config) IsEnvFalse(key string) bool {
-	value := c.Getenv(key)
+func envFalse(value string) bool {
 	return value == "0" || value == "n" || value == "no" || value == "off" || value == "false"
 }
 
@@ -375,6 +498,19 @@ func (c *config) EnvDeps() map[string]string {
 	return c.envDeps
 }
 
+func (c *config) EnvReaders() map[string][]string {
+	c.envLock.Lock()
+	defer c.envLock.Unlock()
+	ret := make(map[string][]string, len(c.envReaders))
+	for key, readers := range c.envReaders {
+		for reader := range readers {
+			ret[key] = append(ret[key], reader)
+		}
+		sort.Strings(ret[key])
+	}
+	return ret
+}
+
 func (c *config) EmbeddedInMake() bool {
 	return c.inMake
 }
@@ -620,6 +756,10 @@ func (c *config) HostStaticBinaries() bool {
 	return Bool(c.productVariables.HostStaticBinaries)
 }
 
+func (c *config) HostArm64GlibcGccRoot() string {
+	return String(c.productVariables.HostArm64GlibcGccRoot)
+}
+
 func (c *deviceConfig) Arches() []Arch {
 	var arches []Arch
 	for _, target := range c.config.Targets[Device] {
@@ -680,6 +820,13 @@ func (c *deviceConfig) ProductPath() string {
 	return "product"
 }
 
+func (c *deviceConfig) SystemExtPath() string {
+	if c.config.productVariables.SystemExtPath != nil {
+		return *c.config.productVariables.SystemExtPath
+	}
+	return "system_ext"
+}
+
 func (c *deviceConfig) BtConfigIncludeDir() string {
 	return String(c.config.productVariables.BtConfigIncludeDir)
 }
@@ -736,6 +883,16 @@ func (c *config) VendorConfig(name string) VendorConfig {
 	return vendorConfig(c.productVariables.VendorVars[name])
 }
 
+func (c *config) PreferPrebuiltForPath(path string) bool {
+	for _, prefix := range c.productVariables.PreferPrebuiltPaths {
+		prefix = strings.TrimSuffix(prefix, "/")
+		if path == prefix || strings.HasPrefix(path, prefix+"/") {
+			return true
+		}
+	}
+	return false
+}
+
 func (c vendorConfig) Bool(name string) bool {
 	v := strings.ToLower(c[name])
 	return v == "1" || v == "y" || v == "yes" || v == "on" || v == "true"
@@ -0,0 +1,206 @@
+package android
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"reflect"
+	"sort"
+	"strings"
+)
+
+func init() {
+	RegisterSingletonType("product_variables_schema", ProductVariablesSchemaSingleton)
+}
+
+func jsonFieldName(field reflect.StructField) (string, bool) {
+	if field.PkgPath != "" {
+		return "", false
+	}
+	tag := field.Tag.Get("json")
+	if tag == "-" {
+		return "", false
+	}
+	if name := strings.Split(tag, ",")[0]; name != "" {
+		return name, true
+	}
+	return field.Name, true
+}
+
+func jsonFieldNames(t reflect.Type) []string {
+	var names []string
+	for i := 0; i < t.NumField(); i++ {
+		if name, ok := jsonFieldName(t.Field(i)); ok {
+			names = append(names, name)
+		}
+	}
+	return names
+}
+
+func decodeJsonConfigStrict(data []byte, configurable jsonConfigurable) error {
+	var raw map[string]json.RawMessage
+	if err := json.Unmarshal(data, &raw); err != nil {
+		return jsonDecodeError(data, err)
+	}
+
+	known := jsonFieldNames(reflect.TypeOf(configurable).Elem())
+	var unknown []string
+	for key := range raw {
+		if !InList(key, known) {
+			unknown = append(unknown, key)
+		}
+	}
+	if len(unknown) > 0 {
+		sort.Strings(unknown)
+		var msgs []string
+		for _, key := range unknown {
+			msg := fmt.Sprintf("unknown field %q", key)
+			if suggestion := closestString(key, known); suggestion != "" {
+				msg += fmt.Sprintf(", did you mean %q?", suggestion)
+			}
+			msgs = append(msgs, msg)
+		}
+		return fmt.Errorf("%s", strings.Join(msgs, "\n"))
+	}
+
+	decoder := json.NewDecoder(bytes.NewReader(data))
+	decoder.DisallowUnknownFields()
+	if err := decoder.Decode(configurable); err != nil {
+		return jsonDecodeError(data, err)
+	}
+	return nil
+}
+
+func jsonDecodeError(data []byte, err error) error {
+	switch err := err.(type) {
+	case *json.SyntaxError:
+		return fmt.Errorf("line %d: %s", jsonLine(data, err.Offset), err.Error())
+	case *json.UnmarshalTypeError:
+		return fmt.Errorf("line %d: field %q: cannot use JSON %s as %s",
+			jsonLine(data, err.Offset), err.Field, err.Value, err.Type.String())
+	}
+	return err
+}
+
+func jsonLine(data []byte, offset int64) int {
+	if offset > int64(len(data)) {
+		offset = int64(len(data))
+	}
+	return bytes.Count(data[:offset], []byte("\n")) + 1
+}
+
+func closestString(s string, candidates []string) string {
+	best := ""
+	bestDistance := len(s)/3 + 2
+	for _, c := range candidates {
+		d := editDistance(strings.ToLower(s), strings.ToLower(c))
+		if d < bestDistance {
+			best = c
+			bestDistance = d
+		}
+	}
+	return best
+}
+
+func editDistance(a, b string) int {
+	prev := make([]int, len(b)+1)
+	cur := make([]int, len(b)+1)
+	for j := range prev {
+		prev[j] = j
+	}
+	for i := 1; i <= len(a); i++ {
+		cur[0] = i
+		for j := 1; j <= len(b); j++ {
+			cost := 1
+			if a[i-1] == b[j-1] {
+				cost = 0
+			}
+			cur[j] = minInt(prev[j]+1, cur[j-1]+1, prev[j-1]+cost)
+		}
+		prev, cur = cur, prev
+	}
+	return prev[len(b)]
+}
+
+func minInt(values ...int) int {
+	ret := values[0]
+	for _, v := range values[1:] {
+		if v < ret {
+			ret = v
+		}
+	}
+	return ret
+}
+
+func jsonSchemaForType(t reflect.Type) map[string]interface{} {
+	for t.Kind() == reflect.Ptr {
+		t = t.Elem()
+	}
+
+	switch t.Kind() {
+	case reflect.Bool:
+		return map[string]interface{}{"type": "boolean"}
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64,
+		reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		return map[string]interface{}{"type": "integer"}
+	case reflect.String:
+		return map[string]interface{}{"type": "string"}
+	case reflect.Slice:
+		return map[string]interface{}{"type": "array", "items": jsonSchemaForType(t.Elem())}
+	case reflect.Map:
+		return map[string]interface{}{"type": "object", "additionalProperties": jsonSchemaForType(t.Elem())}
+	case reflect.Struct:
+		properties := make(map[string]interface{})
+		for i := 0; i < t.NumField(); i++ {
+			field := t.Field(i)
+			if name, ok := jsonFieldName(field); ok {
+				properties[name] = jsonSchemaForType(field.Type)
+			}
+		}
+		return map[string]interface{}{
+			"type":                 "object",
+			"properties":           properties,
+			"additionalProperties": false,
+		}
+	}
+	return map[string]interface{}{}
+}
+
+func ProductVariablesSchema() ([]byte, error) {
+	schema := jsonSchemaForType(reflect.TypeOf(productVariables{}))
+	schema["$schema"] = "http://json-schema.org/draft-07/schema#"
+	schema["title"] = productVariablesFileName
+
+	data, err := json.MarshalIndent(schema, "", "    ")
+	if err != nil {
+		return nil, err
+	}
+	return append(data, '\n'), nil
+}
+
+func (c *config) ProductVariablesJson() ([]byte, error) {
+	data, err := json.MarshalIndent(&c.productVariables, "", "    ")
+	if err != nil {
+		return nil, err
+	}
+	return append(data, '\n'), nil
+}
+
+func ProductVariablesSchemaSingleton() Singleton {
+	return &productVariablesSchemaSingleton{}
+}
+
+type productVariablesSchemaSingleton struct{}
+
+func (s *productVariablesSchemaSingleton) GenerateBuildActions(ctx SingletonContext) {
+	data, err := ProductVariablesSchema()
+	if err != nil {
+		ctx.Errorf("%s", err.Error())
+		return
+	}
+
+	schemaFile := PathForOutput(ctx, productVariablesFileName+".schema.json").String()
+	if err := writeFileIfChanged(schemaFile, data); err != nil {
+		ctx.Errorf("%s", err.Error())
+	}
+}
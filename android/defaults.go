@@ -1,10 +1,17 @@
 package android
 
 import (
+	"reflect"
+	"strings"
+
 	"github.com/google/blueprint"
 	"github.com/google/blueprint/proptools"
 )
 
+func init() {
+	RegisterSingletonType("exclusions", ExclusionsSingleton)
+}
+
 type defaultsDependencyTag struct {
 	blueprint.BaseDependencyTag
 }
@@ -18,20 +25,41 @@ type defaultsProperties struct {
 type DefaultableModuleBase struct {
 	defaultsProperties    defaultsProperties
 	defaultableProperties []interface{}
+	exclusionProperties   []interface{}
+	matchedExclusions     map[exclusion]bool
+}
+
+// exclusion is a single entry of an exclude_* property.
+type exclusion struct {
+	property string
+	entry    string
 }
 
 func (d *DefaultableModuleBase) defaults() *defaultsProperties {
 	return &d.defaultsProperties
 }
 
+func (d *DefaultableModuleBase) defaultablePropertyStructs() []interface{} {
+	return d.defaultableProperties
+}
+
 func (d *DefaultableModuleBase) setProperties(props []interface{}) {
 	d.defaultableProperties = props
 }
 
+func (d *DefaultableModuleBase) addExclusionProperties(props []interface{}) {
+	d.exclusionProperties = append(d.exclusionProperties, props...)
+}
+
 type Defaultable interface {
 	defaults() *defaultsProperties
 	setProperties([]interface{})
+	defaultablePropertyStructs() []interface{}
+	addExclusionProperties([]interface{})
 	applyDefaults(TopDownMutatorContext, []Defaults)
+	applyExclusions()
+	exclusionsMatchedByVariant() map[exclusion]bool
+	forEachExclusion(func(property, target string, excluded []string))
 }
 
 type DefaultableModule interface {
@@ -47,6 +75,11 @@ func InitDefaultableModule(module DefaultableModule) {
 	module.AddProperties(module.defaults())
 }
 
+func InitDefaultsExclusions(module DefaultableModule, props ...interface{}) {
+	module.AddProperties(props...)
+	module.addExclusionProperties(props)
+}
+
 type DefaultsModuleBase struct {
 	DefaultableModuleBase
 	defaultProperties []interface{}
@@ -103,6 +136,52 @@ func (defaultable *DefaultableModuleBase) applyDefaults(ctx TopDownMutatorContex
 	}
 }
 
+func (defaultable *DefaultableModuleBase) forEachExclusion(f func(property, target string, excluded []string)) {
+	for _, exclusions := range defaultable.exclusionProperties {
+		v := reflect.ValueOf(exclusions).Elem()
+		for i := 0; i < v.NumField(); i++ {
+			field := v.Type().Field(i)
+			excluded, ok := v.Field(i).Interface().([]string)
+			if !ok || len(excluded) == 0 || !strings.HasPrefix(field.Name, "Exclude_") {
+				continue
+			}
+
+			target := strings.TrimPrefix(field.Name, "Exclude_")
+			target = strings.ToUpper(target[:1]) + target[1:]
+			f(field.Name, target, excluded)
+		}
+	}
+}
+
+// applyExclusions removes excluded entries from the properties of this variant after arch and
+// product variable properties have been merged into them, and records which entries matched.
+func (defaultable *DefaultableModuleBase) applyExclusions() {
+	defaultable.matchedExclusions = make(map[exclusion]bool)
+
+	defaultable.forEachExclusion(func(property, target string, excluded []string) {
+		for _, prop := range defaultable.defaultableProperties {
+			list := reflect.ValueOf(prop).Elem().FieldByName(target)
+			if !list.IsValid() || list.Type() != reflect.TypeOf([]string(nil)) {
+				continue
+			}
+
+			var kept []string
+			for _, s := range list.Interface().([]string) {
+				if InList(s, excluded) {
+					defaultable.matchedExclusions[exclusion{property, s}] = true
+				} else {
+					kept = append(kept, s)
+				}
+			}
+			list.Set(reflect.ValueOf(kept))
+		}
+	})
+}
+
+func (defaultable *DefaultableModuleBase) exclusionsMatchedByVariant() map[exclusion]bool {
+	return defaultable.matchedExclusions
+}
+
 func RegisterDefaultsPreArchMutators(ctx RegisterMutatorsContext) {
 	ctx.BottomUp("defaults_deps", defaultsDepsMutator).Parallel()
 	ctx.TopDown("defaults", defaultsMutator).Parallel()
@@ -115,7 +194,12 @@ func defaultsDepsMutator(ctx BottomUpMutatorContext) {
 }
 
 func defaultsMutator(ctx TopDownMutatorContext) {
-	if defaultable, ok := ctx.Module().(Defaultable); ok && len(defaultable.defaults().Defaults) > 0 {
+	defaultable, ok := ctx.Module().(Defaultable)
+	if !ok {
+		return
+	}
+
+	if len(defaultable.defaults().Defaults) > 0 {
 		var defaultsList []Defaults
 		ctx.WalkDeps(func(module, parent Module) bool {
 			if ctx.OtherModuleDependencyTag(module) == DefaultsDepTag {
@@ -132,3 +216,42 @@ func defaultsMutator(ctx TopDownMutatorContext) {
 		defaultable.applyDefaults(ctx, defaultsList)
 	}
 }
+
+func exclusionsMutator(ctx BottomUpMutatorContext) {
+	if defaultable, ok := ctx.Module().(Defaultable); ok {
+		defaultable.applyExclusions()
+	}
+}
+
+func ExclusionsSingleton() Singleton {
+	return &exclusionsSingleton{}
+}
+
+type exclusionsSingleton struct{}
+
+// An exclusion may only match entries of some variants, for example an entry in arch: { arm: {} },
+// so it is only an error if it doesn't match an entry in any variant of the module.
+func (s *exclusionsSingleton) GenerateBuildActions(ctx SingletonContext) {
+	ctx.VisitAllModules(func(module Module) {
+		defaultable, ok := module.(Defaultable)
+		if !ok || ctx.PrimaryModule(module) != module {
+			return
+		}
+
+		matched := make(map[exclusion]bool)
+		ctx.VisitAllModuleVariants(module, func(variant Module) {
+			for e := range variant.(Defaultable).exclusionsMatchedByVariant() {
+				matched[e] = true
+			}
+		})
+
+		defaultable.forEachExclusion(func(property, target string, excluded []string) {
+			for _, s := range excluded {
+				if !matched[exclusion{property, s}] {
+					ctx.ModuleErrorf(module, "%s: %q does not match any entry in %s",
+						proptools.PropertyNameForField(property), s, proptools.PropertyNameForField(target))
+				}
+			}
+		})
+	})
+}
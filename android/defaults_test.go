@@ -0,0 +1,229 @@
+package android
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/google/blueprint"
+)
+
+type mockExclusionsProperties struct {
+	Static_libs []string `android:"arch_variant"`
+
+	Nested struct {
+		Static_libs []string
+	}
+}
+
+type mockExcludeProperties struct {
+	Exclude_static_libs []string
+}
+
+type mockExclusionsModule struct {
+	ModuleBase
+	DefaultableModuleBase
+
+	properties        mockExclusionsProperties
+	excludeProperties mockExcludeProperties
+}
+
+func mockExclusionsModuleFactory() Module {
+	m := &mockExclusionsModule{}
+	m.AddProperties(&m.properties)
+	InitDefaultsExclusions(m, &m.excludeProperties)
+	InitAndroidArchModule(m, HostAndDeviceSupported, MultilibBoth)
+	InitDefaultableModule(m)
+	return m
+}
+
+func (m *mockExclusionsModule) DepsMutator(ctx BottomUpMutatorContext) {
+}
+
+func (m *mockExclusionsModule) GenerateAndroidBuildActions(ctx ModuleContext) {
+}
+
+type mockExclusionsDefaults struct {
+	ModuleBase
+	DefaultsModuleBase
+}
+
+func mockExclusionsDefaultsFactory() Module {
+	m := &mockExclusionsDefaults{}
+	m.AddProperties(&mockExclusionsProperties{}, &mockExcludeProperties{})
+	InitDefaultsModule(m)
+	return m
+}
+
+func (m *mockExclusionsDefaults) DepsMutator(ctx BottomUpMutatorContext) {
+}
+
+func (m *mockExclusionsDefaults) GenerateAndroidBuildActions(ctx ModuleContext) {
+}
+
+func testExclusions(bp string) (*TestContext, []error) {
+	config := TestArchConfig(buildDir, nil)
+
+	ctx := NewTestArchContext()
+	ctx.RegisterModuleType("mock_library", ModuleFactoryAdaptor(mockExclusionsModuleFactory))
+	ctx.RegisterModuleType("mock_defaults", ModuleFactoryAdaptor(mockExclusionsDefaultsFactory))
+	ctx.PreArchMutators(RegisterDefaultsPreArchMutators)
+	ctx.RegisterSingletonType("exclusions", SingletonFactoryAdaptor(ExclusionsSingleton))
+	ctx.Register()
+
+	ctx.MockFileSystem(map[string][]byte{
+		"Android.bp": []byte(bp),
+	})
+
+	_, errs := ctx.ParseFileList(".", []string{"Android.bp"})
+	if len(errs) > 0 {
+		return ctx, errs
+	}
+	_, errs = ctx.ResolveDependencies(config)
+	if len(errs) > 0 {
+		return ctx, errs
+	}
+	_, errs = ctx.PrepareBuildActions(config)
+	return ctx, errs
+}
+
+func TestExclusions(t *testing.T) {
+	testCases := []struct {
+		name string
+		bp   string
+
+		// expected static_libs of libexample, and of its arm64 variant if different
+		staticLibs      []string
+		arm64StaticLibs []string
+		nestedLibs      []string
+		err             string
+	}{
+		{
+			name: "entry from defaults",
+			bp: `
+				mock_defaults {
+					name: "defaults",
+					static_libs: ["liba", "libb"],
+				}
+
+				mock_library {
+					name: "libexample",
+					defaults: ["defaults"],
+					exclude_static_libs: ["libb"],
+				}`,
+			staticLibs: []string{"liba"},
+		},
+		{
+			name: "arch entry from defaults",
+			bp: `
+				mock_defaults {
+					name: "defaults",
+					static_libs: ["liba"],
+					arch: {
+						arm64: {
+							static_libs: ["libarm64", "libc"],
+						},
+					},
+				}
+
+				mock_library {
+					name: "libexample",
+					defaults: ["defaults"],
+					exclude_static_libs: ["libarm64"],
+				}`,
+			staticLibs:      []string{"liba"},
+			arm64StaticLibs: []string{"liba", "libc"},
+		},
+		{
+			name: "target entry of the module",
+			bp: `
+				mock_defaults {
+					name: "defaults",
+					static_libs: ["liba"],
+				}
+
+				mock_library {
+					name: "libexample",
+					defaults: ["defaults"],
+					target: {
+						host: {
+							static_libs: ["libhost"],
+						},
+					},
+					exclude_static_libs: ["libhost"],
+				}`,
+			staticLibs: []string{"liba"},
+		},
+		{
+			name: "exclusion in defaults",
+			bp: `
+				mock_defaults {
+					name: "defaults",
+					static_libs: ["liba", "libb"],
+					exclude_static_libs: ["libb"],
+				}
+
+				mock_library {
+					name: "libexample",
+					defaults: ["defaults"],
+				}`,
+			staticLibs: []string{"liba"},
+		},
+		{
+			name: "nested properties with the same name are not affected",
+			bp: `
+				mock_library {
+					name: "libexample",
+					static_libs: ["liba"],
+					nested: {
+						static_libs: ["liba"],
+					},
+					exclude_static_libs: ["liba"],
+				}`,
+			nestedLibs: []string{"liba"},
+		},
+		{
+			name: "exclusion that matches nothing",
+			bp: `
+				mock_library {
+					name: "libexample",
+					static_libs: ["liba"],
+					exclude_static_libs: ["libmissing"],
+				}`,
+			err: `exclude_static_libs: "libmissing" does not match any entry in static_libs`,
+		},
+	}
+
+	for _, testCase := range testCases {
+		t.Run(testCase.name, func(t *testing.T) {
+			ctx, errs := testExclusions(testCase.bp)
+			if testCase.err != "" {
+				FailIfNoMatchingErrors(t, testCase.err, errs)
+				return
+			}
+			FailIfErrored(t, errs)
+
+			variants := 0
+			ctx.VisitAllModules(func(m blueprint.Module) {
+				module, ok := m.(*mockExclusionsModule)
+				if !ok || ctx.ModuleName(m) != "libexample" {
+					return
+				}
+				variants++
+
+				expected := testCase.staticLibs
+				if testCase.arm64StaticLibs != nil && module.Target().Arch.ArchType == Arm64 {
+					expected = testCase.arm64StaticLibs
+				}
+				if got := module.properties.Static_libs; !reflect.DeepEqual(got, expected) {
+					t.Errorf("%s: expected static_libs %q, got %q", ctx.ModuleSubDir(m), expected, got)
+				}
+				if got := module.properties.Nested.Static_libs; !reflect.DeepEqual(got, testCase.nestedLibs) {
+					t.Errorf("%s: expected nested static_libs %q, got %q", ctx.ModuleSubDir(m), testCase.nestedLibs, got)
+				}
+			})
+			if variants == 0 {
+				t.Errorf("no variants of libexample found")
+			}
+		})
+	}
+}
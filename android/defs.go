@@ -11,6 +11,8 @@ var (
 	cpPreserveSymlinks = pctx.VariableConfigMethod("cpPreserveSymlinks",
 		Config.CpPreserveSymlinksFlags)
 
+	bashCmd = pctx.HostToolVariable("bashCmd", "bash")
+
 	Phony = pctx.AndroidStaticRule("Phony",
 		blueprint.RuleParams{
 			Command:     "# phony $out",
@@ -66,7 +68,7 @@ var (
 
 	WriteFile = pctx.AndroidStaticRule("WriteFile",
 		blueprint.RuleParams{
-			Command:     "/data/data/com.termux/files/usr/bin/bash -c 'echo -e $$0 > $out' '$content'",
+			Command:     "$bashCmd -c 'echo -e $$0 > $out' '$content'",
 			Description: "writing file $out",
 		},
 		"content")
@@ -76,4 +78,6 @@ var (
 
 func init() {
 	pctx.Import("github.com/google/blueprint/bootstrap")
+
+	RequireHostTool("bash")
 }
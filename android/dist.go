@@ -0,0 +1,237 @@
+package android
+
+import (
+	"fmt"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"github.com/google/blueprint"
+)
+
+func init() {
+	RegisterSingletonType("dist", DistSingleton)
+}
+
+type Dist struct {
+	Targets []string
+	Dir     *string
+	Dest    *string
+}
+
+// parseDist parses an entry of the dists property, which has the form <target>[:<path>]. path is
+// relative to $DIST_DIR, the outputs are copied into it under their own names if it ends in /.
+func parseDist(entry string) (Dist, error) {
+	target, path := entry, ""
+	if i := strings.Index(entry, ":"); i >= 0 {
+		target, path = entry[:i], entry[i+1:]
+	}
+	if target == "" {
+		return Dist{}, fmt.Errorf("invalid dists entry %q, must be of the form <target>[:<path>]", entry)
+	}
+
+	dist := Dist{Targets: []string{target}}
+	if path != "" {
+		dir, dest := filepath.Split(path)
+		if dir != "" {
+			dist.Dir = stringPtr(filepath.Clean(dir))
+		}
+		if dest != "" {
+			dist.Dest = stringPtr(dest)
+		}
+	}
+	return dist, nil
+}
+
+func (a *ModuleBase) dists() ([]Dist, []error) {
+	var dists []Dist
+	var errs []error
+	if len(a.commonProperties.Dist.Targets) > 0 {
+		dists = append(dists, a.commonProperties.Dist)
+	}
+	for _, entry := range a.commonProperties.Dists {
+		dist, err := parseDist(entry)
+		if err != nil {
+			errs = append(errs, err)
+			continue
+		}
+		dists = append(dists, dist)
+	}
+	return dists, errs
+}
+
+type OutputFileProducer interface {
+	OutputFiles() Paths
+}
+
+func (a *ModuleBase) distOutputs() Paths {
+	var paths Paths
+	if producer, ok := a.module.(OutputFileProducer); ok {
+		paths = producer.OutputFiles()
+	} else if producer, ok := a.module.(SourceFileProducer); ok {
+		paths = producer.Srcs()
+	}
+
+	var outputs Paths
+	for _, path := range paths {
+		if path != nil {
+			outputs = append(outputs, path)
+		}
+	}
+	return outputs
+}
+
+func (a *ModuleBase) generateDistRules(ctx *androidModuleContext) {
+	a.distFiles = nil
+
+	// All variants of a module would copy their outputs to the same dist paths, so only the
+	// first variant is copied.
+	if ctx.PrimaryModule() != a.module {
+		return
+	}
+
+	dists, errs := a.dists()
+	for _, err := range errs {
+		ctx.PropertyErrorf("dists", "%s", err.Error())
+	}
+	if len(dists) == 0 {
+		return
+	}
+
+	outputs := a.distOutputs()
+	if len(outputs) == 0 {
+		ctx.PropertyErrorf("dist", "module has no outputs to dist")
+		return
+	}
+
+	distDirSet := PathForDist(ctx, "").Valid()
+
+	a.distFiles = make(map[string]Paths)
+	for i, dist := range dists {
+		property := "dist"
+		if len(a.commonProperties.Dist.Targets) == 0 || i > 0 {
+			property = "dists"
+		}
+
+		if len(dist.Targets) == 0 {
+			ctx.PropertyErrorf(property, "targets must not be empty")
+			continue
+		}
+		if dist.Dest != nil && len(outputs) > 1 {
+			ctx.PropertyErrorf(property, "dest may only be set for modules with a single output, found %q",
+				outputs.Strings())
+			continue
+		}
+
+		for _, output := range outputs {
+			dest := output.Base()
+			if dist.Dest != nil {
+				dest = *dist.Dest
+			}
+			if strings.Contains(dest, "/") {
+				ctx.PropertyErrorf(property, "dest %q must be a file name, use dir for subdirectories", dest)
+				continue
+			}
+			if !distDirSet {
+				continue
+			}
+
+			distPath := PathForDist(ctx, filepath.Join(String(dist.Dir), dest))
+			ctx.Build(pctx, BuildParams{
+				Rule:        Cp,
+				Description: "dist " + distPath.RelPathString(),
+				Input:       output,
+				Output:      distPath,
+			})
+
+			for _, target := range dist.Targets {
+				a.distFiles[target] = append(a.distFiles[target], distPath)
+			}
+		}
+	}
+}
+
+func DistSingleton() Singleton {
+	return &distSingleton{}
+}
+
+type distSingleton struct{}
+
+func (s *distSingleton) GenerateBuildActions(ctx SingletonContext) {
+	suffix := ""
+	if ctx.Config().EmbeddedInMake() {
+		suffix = "-soong"
+	}
+
+	if !PathForDist(ctx, "").Valid() {
+		var names []string
+		ctx.VisitAllModules(func(module Module) {
+			dists, _ := module.base().dists()
+			for _, dist := range dists {
+				names = append(names, dist.Targets...)
+			}
+		})
+		if len(names) == 0 {
+			return
+		}
+		names = FirstUniqueStrings(append(names, "dist"))
+		sort.Strings(names)
+		for _, goal := range names {
+			ctx.Build(pctx, BuildParams{
+				Rule:        ErrorRule,
+				Description: "dist " + goal,
+				Output:      PathForPhony(ctx, goal+suffix),
+				Args: map[string]string{
+					"error": fmt.Sprintf("DIST_DIR is not set, cannot build dist goal %q", goal),
+				},
+			})
+		}
+		return
+	}
+
+	goals := make(map[string]Paths)
+	owners := make(map[string]string)
+
+	ctx.VisitAllModules(func(module Module) {
+		for goal, files := range module.base().distFiles {
+			for _, file := range files {
+				owner := moduleGraphId(ctx, module)
+				if prev, exists := owners[file.String()]; exists && prev != owner {
+					ctx.Errorf("dist file %s is produced by both %q and %q", file.String(), prev, owner)
+					continue
+				}
+				owners[file.String()] = owner
+				goals[goal] = append(goals[goal], file)
+			}
+		}
+	})
+
+	if len(goals) == 0 {
+		return
+	}
+
+	var names []string
+	for goal := range goals {
+		names = append(names, goal)
+	}
+	sort.Strings(names)
+
+	var all Paths
+	for _, goal := range names {
+		files := FirstUniquePaths(goals[goal])
+		all = append(all, files...)
+		ctx.Build(pctx, BuildParams{
+			Rule:      blueprint.Phony,
+			Output:    PathForPhony(ctx, goal+suffix),
+			Implicits: files,
+		})
+	}
+
+	if !InList("dist", names) {
+		ctx.Build(pctx, BuildParams{
+			Rule:      blueprint.Phony,
+			Output:    PathForPhony(ctx, "dist"+suffix),
+			Implicits: FirstUniquePaths(all),
+		})
+	}
+}
@@ -0,0 +1,53 @@
+package android
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestParseDist(t *testing.T) {
+	testCases := []struct {
+		in  string
+		out Dist
+		err bool
+	}{
+		{
+			in:  "droidcore",
+			out: Dist{Targets: []string{"droidcore"}},
+		},
+		{
+			in:  "droidcore:foo.apk",
+			out: Dist{Targets: []string{"droidcore"}, Dest: stringPtr("foo.apk")},
+		},
+		{
+			in:  "sdk:tools/",
+			out: Dist{Targets: []string{"sdk"}, Dir: stringPtr("tools")},
+		},
+		{
+			in:  "sdk:tools/lib/foo.jar",
+			out: Dist{Targets: []string{"sdk"}, Dir: stringPtr("tools/lib"), Dest: stringPtr("foo.jar")},
+		},
+		{
+			in:  ":foo.apk",
+			err: true,
+		},
+	}
+
+	for _, testCase := range testCases {
+		t.Run(testCase.in, func(t *testing.T) {
+			out, err := parseDist(testCase.in)
+			if testCase.err {
+				if err == nil {
+					t.Errorf("expected an error, got %#v", out)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("unexpected error: %s", err)
+			}
+			if !reflect.DeepEqual(out, testCase.out) {
+				t.Errorf("expected %#v, got %#v", testCase.out, out)
+			}
+		})
+	}
+}
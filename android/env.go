@@ -28,13 +28,14 @@ type envSingleton struct{}
 
 func (c *envSingleton) GenerateBuildActions(ctx SingletonContext) {
 	envDeps := ctx.Config().EnvDeps()
+	envReaders := ctx.Config().EnvReaders()
 
 	envFile := PathForOutput(ctx, ".soong.environment")
 	if ctx.Failed() {
 		return
 	}
 
-	err := env.WriteEnvFile(envFile.String(), envDeps)
+	err := env.WriteEnvFile(envFile.String(), envDeps, envReaders)
 	if err != nil {
 		ctx.Errorf(err.Error())
 	}
@@ -0,0 +1,255 @@
+package android
+
+import (
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+
+	"github.com/google/blueprint"
+)
+
+const DefaultHostToolsPrefix = "/data/data/com.termux/files/usr"
+
+type HostToolSpec struct {
+	Path    string `json:",omitempty"`
+	Version string `json:",omitempty"`
+}
+
+type HostToolResolver struct {
+	prefix     string
+	searchPath []string
+	toolMap    map[string]HostToolSpec
+
+	lock    sync.Mutex
+	results map[string]hostToolResult
+}
+
+type hostToolResult struct {
+	path  string
+	found bool
+	deps  []string
+}
+
+func NewHostToolResolver(prefix string, searchPath []string, toolMap map[string]HostToolSpec) *HostToolResolver {
+	if prefix == "" {
+		prefix = DefaultHostToolsPrefix
+	}
+	return &HostToolResolver{
+		prefix:     prefix,
+		searchPath: searchPath,
+		toolMap:    toolMap,
+	}
+}
+
+func NewHostToolResolverFromEnv(getenv func(string) string) (*HostToolResolver, error) {
+	var searchPath []string
+	if v := getenv("SOONG_HOST_TOOLS_USE_PATH"); v == "1" || v == "true" {
+		searchPath = filepath.SplitList(getenv("PATH"))
+	}
+
+	var toolMap map[string]HostToolSpec
+	if file := getenv("SOONG_HOST_TOOLS_MAP"); file != "" {
+		var err error
+		toolMap, err = LoadHostToolMap(file)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	return NewHostToolResolver(getenv("SOONG_HOST_TOOLS_PREFIX"), searchPath, toolMap), nil
+}
+
+func LoadHostToolMap(filename string) (map[string]HostToolSpec, error) {
+	data, err := ioutil.ReadFile(filename)
+	if err != nil {
+		return nil, fmt.Errorf("host tool map: could not read %s: %s", filename, err.Error())
+	}
+
+	toolMap := make(map[string]HostToolSpec)
+	if err := json.Unmarshal(data, &toolMap); err != nil {
+		return nil, fmt.Errorf("host tool map: %s did not parse correctly: %s", filename, err.Error())
+	}
+
+	return toolMap, nil
+}
+
+func (r *HostToolResolver) Prefix() string {
+	return r.prefix
+}
+
+func (r *HostToolResolver) Bin() string {
+	return filepath.Join(r.prefix, "bin")
+}
+
+func (r *HostToolResolver) lookup(name string) (string, bool) {
+	r.lock.Lock()
+	defer r.lock.Unlock()
+
+	result, ok := r.results[name]
+	if !ok {
+		result = r.search(name)
+		if r.results == nil {
+			r.results = make(map[string]hostToolResult)
+		}
+		r.results[name] = result
+	}
+	return result.path, result.found
+}
+
+func (r *HostToolResolver) search(name string) hostToolResult {
+	if spec, ok := r.toolMap[name]; ok && spec.Path != "" {
+		if !isExecutable(spec.Path) {
+			return hostToolResult{path: spec.Path}
+		}
+		return hostToolResult{path: spec.Path, found: true, deps: []string{spec.Path}}
+	}
+
+	var deps []string
+	for _, dir := range append([]string{r.Bin()}, r.searchPath...) {
+		path := filepath.Join(dir, name)
+		if isExecutable(path) {
+			return hostToolResult{path: path, found: true, deps: append(deps, path)}
+		}
+		if s, err := os.Stat(dir); err == nil && s.IsDir() {
+			deps = append(deps, dir)
+		}
+	}
+
+	return hostToolResult{path: filepath.Join(r.Bin(), name), deps: deps}
+}
+
+func (r *HostToolResolver) resolvedTools() (names []string, deps []string) {
+	r.lock.Lock()
+	defer r.lock.Unlock()
+
+	for name, result := range r.results {
+		names = append(names, name)
+		deps = append(deps, result.deps...)
+	}
+	sort.Strings(names)
+	deps = FirstUniqueStrings(deps)
+	sort.Strings(deps)
+	return names, deps
+}
+
+func (r *HostToolResolver) Resolve(name string) string {
+	path, _ := r.lookup(name)
+	return path
+}
+
+func (r *HostToolResolver) Check(name string) error {
+	path, found := r.lookup(name)
+	if !found {
+		return fmt.Errorf("host tool %q not found (looked for %s)", name, path)
+	}
+
+	spec, ok := r.toolMap[name]
+	if !ok || spec.Version == "" {
+		return nil
+	}
+
+	output, err := exec.Command(path, "--version").CombinedOutput()
+	if err != nil {
+		return fmt.Errorf("host tool %q: failed to run %s --version: %s", name, path, err.Error())
+	}
+
+	firstLine := strings.SplitN(string(output), "\n", 2)[0]
+	if !strings.Contains(firstLine, spec.Version) {
+		return fmt.Errorf("host tool %q: %s reports version %q, expected %q",
+			name, path, strings.TrimSpace(firstLine), spec.Version)
+	}
+
+	return nil
+}
+
+func isExecutable(path string) bool {
+	s, err := os.Stat(path)
+	if err != nil {
+		return false
+	}
+	return !s.IsDir() && s.Mode()&0111 != 0
+}
+
+var requiredHostTools = struct {
+	sync.Mutex
+	names map[string]bool
+}{names: make(map[string]bool)}
+
+func RequireHostTool(names ...string) {
+	requiredHostTools.Lock()
+	defer requiredHostTools.Unlock()
+	for _, name := range names {
+		requiredHostTools.names[name] = true
+	}
+}
+
+func RequiredHostTools() []string {
+	requiredHostTools.Lock()
+	defer requiredHostTools.Unlock()
+	names := make([]string, 0, len(requiredHostTools.names))
+	for name := range requiredHostTools.names {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+func (p PackageContext) HostToolVariable(name, tool string) blueprint.Variable {
+	return p.VariableFunc(name, func(ctx PackageVarContext) string {
+		return ctx.Config().HostTool(tool)
+	})
+}
+
+func (p PackageContext) HostToolsPrefixVariable(name, rel string) blueprint.Variable {
+	return p.VariableFunc(name, func(ctx PackageVarContext) string {
+		return filepath.Join(ctx.Config().HostToolsPrefix(), rel)
+	})
+}
+
+// BootstrapHostTool is implemented by host tools that are built by blueprint bootstrap instead
+// of by an android module, like bootstrap_go_binary modules.
+type BootstrapHostTool interface {
+	blueprint.Module
+	InstallPath() string
+}
+
+func PathForBootstrapHostTool(ctx PathContext, tool BootstrapHostTool) (OutputPath, error) {
+	rel, err := filepath.Rel(PathForOutput(ctx).String(), tool.InstallPath())
+	if err != nil {
+		return OutputPath{}, err
+	}
+	return PathForOutput(ctx, rel), nil
+}
+
+func HostToolsSingleton() Singleton {
+	return &hostToolsSingleton{}
+}
+
+type hostToolsSingleton struct{}
+
+func (s *hostToolsSingleton) GenerateBuildActions(ctx SingletonContext) {
+	usedTools, deps := ctx.Config().hostTools.resolvedTools()
+	ctx.AddNinjaFileDeps(deps...)
+
+	if ctx.Config().SkipHostToolsCheck() {
+		return
+	}
+
+	// Only check the tools that were resolved while generating the build, tools listed in the
+	// host tool map that no rule uses don't need to exist. Required tools are only used through
+	// package variables, which may not have been evaluated yet when this singleton runs.
+	tools := FirstUniqueStrings(append(RequiredHostTools(), usedTools...))
+	sort.Strings(tools)
+
+	for _, name := range tools {
+		if err := ctx.Config().hostTools.Check(name); err != nil {
+			ctx.Errorf("%s", err.Error())
+		}
+	}
+}
@@ -0,0 +1,248 @@
+package android
+
+import (
+	"fmt"
+	"sync"
+
+	"github.com/google/blueprint"
+)
+
+func init() {
+	RegisterModuleType("license_kind", LicenseKindFactory)
+	RegisterModuleType("license", LicenseFactory)
+	RegisterModuleType("package", PackageFactory)
+}
+
+var licenseConditions = []string{
+	"unencumbered",
+	"permissive",
+	"notice",
+	"reciprocal",
+	"restricted",
+	"proprietary",
+	"by_exception_only",
+	"not_allowed",
+}
+
+type licenseKindProperties struct {
+	Conditions []string
+	Url        *string
+}
+
+type licenseKindModule struct {
+	ModuleBase
+
+	properties licenseKindProperties
+}
+
+func LicenseKindFactory() Module {
+	module := &licenseKindModule{}
+	module.AddProperties(&module.properties)
+	InitAndroidModule(module)
+	return module
+}
+
+func (m *licenseKindModule) DepsMutator(ctx BottomUpMutatorContext) {}
+
+func (m *licenseKindModule) GenerateAndroidBuildActions(ctx ModuleContext) {
+	for _, condition := range m.properties.Conditions {
+		if !InList(condition, licenseConditions) {
+			ctx.PropertyErrorf("conditions", "unknown license condition %q, expected one of %q",
+				condition, licenseConditions)
+		}
+	}
+}
+
+type licenseProperties struct {
+	License_kinds    []string
+	Copyright_notice *string
+	Package_name     *string
+	License_text     []string
+}
+
+type licenseModule struct {
+	ModuleBase
+
+	properties licenseProperties
+
+	kinds      []string
+	conditions []string
+	texts      Paths
+}
+
+func LicenseFactory() Module {
+	module := &licenseModule{}
+	module.AddProperties(&module.properties)
+	InitAndroidModule(module)
+	return module
+}
+
+type licenseKindDependencyTag struct {
+	blueprint.BaseDependencyTag
+}
+
+var licenseKindTag licenseKindDependencyTag
+
+func (m *licenseModule) DepsMutator(ctx BottomUpMutatorContext) {
+	ctx.AddDependency(ctx.Module(), licenseKindTag, m.properties.License_kinds...)
+	ExtractSourcesDeps(ctx, m.properties.License_text)
+}
+
+func (m *licenseModule) GenerateAndroidBuildActions(ctx ModuleContext) {
+	m.kinds = nil
+	m.conditions = nil
+	ctx.VisitDirectDepsWithTag(licenseKindTag, func(dep Module) {
+		kind, ok := dep.(*licenseKindModule)
+		if !ok {
+			ctx.PropertyErrorf("license_kinds", "%q is not a license_kind module", ctx.OtherModuleName(dep))
+			return
+		}
+		m.kinds = append(m.kinds, ctx.OtherModuleName(dep))
+		m.conditions = append(m.conditions, kind.properties.Conditions...)
+	})
+	m.conditions = FirstUniqueStrings(m.conditions)
+	m.texts = ctx.ExpandSources(m.properties.License_text, nil)
+}
+
+func (m *licenseModule) PackageName() string {
+	return String(m.properties.Package_name)
+}
+
+type packageProperties struct {
+	Default_applicable_licenses []string
+}
+
+type packageModule struct {
+	ModuleBase
+
+	properties packageProperties
+}
+
+func PackageFactory() Module {
+	module := &packageModule{}
+
+	name := "package"
+	module.nameProperties.Name = &name
+
+	module.AddProperties(&module.properties)
+	return module
+}
+
+func (p *packageModule) Name() string {
+	return *p.nameProperties.Name
+}
+
+func (p *packageModule) DepsMutator(ctx BottomUpMutatorContext) {}
+
+func (p *packageModule) GenerateAndroidBuildActions(ctx ModuleContext) {}
+
+func (p *packageModule) GenerateBuildActions(ctx blueprint.ModuleContext) {}
+
+type packageInfo struct {
+	lock   sync.Mutex
+	module *packageModule
+}
+
+func (p *packageInfo) setModule(module *packageModule) error {
+	p.lock.Lock()
+	defer p.lock.Unlock()
+	if p.module != nil {
+		return fmt.Errorf("only one package module may be declared per directory")
+	}
+	p.module = module
+	return nil
+}
+
+func (p *packageInfo) defaultLicenses() []string {
+	p.lock.Lock()
+	defer p.lock.Unlock()
+	if p.module == nil {
+		return nil
+	}
+	return p.module.properties.Default_applicable_licenses
+}
+
+type licensesDependencyTag struct {
+	blueprint.BaseDependencyTag
+}
+
+var licensesTag licensesDependencyTag
+
+func registerLicensesPreArchMutator(ctx RegisterMutatorsContext) {
+	ctx.BottomUp("package_licenses", packageLicensesMutator).Parallel()
+}
+
+func packageLicensesMutator(ctx BottomUpMutatorContext) {
+	m, ok := ctx.Module().(Module)
+	if !ok {
+		return
+	}
+	base := m.base()
+	if len(base.commonProperties.Licenses) == 0 && base.packageInfo != nil {
+		base.commonProperties.Licenses = base.packageInfo.defaultLicenses()
+	}
+}
+
+func addLicensesDeps(ctx BottomUpMutatorContext, m Module) {
+	switch m.(type) {
+	case *licenseModule, *licenseKindModule, *packageModule:
+		return
+	}
+	ctx.AddDependency(ctx.Module(), licensesTag, m.base().commonProperties.Licenses...)
+}
+
+func (a *ModuleBase) computeLicenses(ctx ModuleContext) {
+	a.licenses = nil
+	for _, dep := range a.directDeps {
+		if dep.tag == licensesTag {
+			license, ok := dep.module.(*licenseModule)
+			if !ok {
+				ctx.PropertyErrorf("licenses", "%q is not a license module", ctx.OtherModuleName(dep.module))
+				continue
+			}
+			a.licenses = append(a.licenses, license)
+			continue
+		}
+
+		switch dependencyKind(dep.tag) {
+		case "static", "whole_static", "java_static_libs":
+			if m, ok := dep.module.(Module); ok {
+				a.licenses = append(a.licenses, m.base().licenses...)
+			}
+		}
+	}
+
+	seen := make(map[*licenseModule]bool)
+	licenses := a.licenses[:0]
+	for _, license := range a.licenses {
+		if !seen[license] {
+			seen[license] = true
+			licenses = append(licenses, license)
+		}
+	}
+	a.licenses = licenses
+}
+
+func (a *ModuleBase) LicenseKinds() []string {
+	var kinds []string
+	for _, license := range a.licenses {
+		kinds = append(kinds, license.kinds...)
+	}
+	return FirstUniqueStrings(kinds)
+}
+
+func (a *ModuleBase) LicenseConditions() []string {
+	var conditions []string
+	for _, license := range a.licenses {
+		conditions = append(conditions, license.conditions...)
+	}
+	return FirstUniqueStrings(conditions)
+}
+
+func (a *ModuleBase) LicenseTexts() Paths {
+	var texts Paths
+	for _, license := range a.licenses {
+		texts = append(texts, license.texts...)
+	}
+	return FirstUniquePaths(texts)
+}
@@ -2,9 +2,8 @@ package android
 
 import (
 	"bytes"
+	"encoding/json"
 	"fmt"
-	"io/ioutil"
-	"os"
 	"strconv"
 
 	"github.com/google/blueprint/proptools"
@@ -68,6 +67,15 @@ type makeVarsVariable struct {
 	value  string
 	sort   bool
 	strict bool
+	pkg    string
+}
+
+type makeVarsJsonVariable struct {
+	Name    string `json:"name"`
+	Value   string `json:"value"`
+	Mode    string `json:"mode"`
+	Sorted  bool   `json:"sorted"`
+	Package string `json:"package"`
 }
 
 func (s *makeVarsSingleton) GenerateBuildActions(ctx SingletonContext) {
@@ -75,7 +83,9 @@ func (s *makeVarsSingleton) GenerateBuildActions(ctx SingletonContext) {
 		return
 	}
 
-	outFile := PathForOutput(ctx, "make_vars"+proptools.String(ctx.Config().productVariables.Make_suffix)+".mk").String()
+	suffix := proptools.String(ctx.Config().productVariables.Make_suffix)
+	outFile := PathForOutput(ctx, "make_vars"+suffix+".mk").String()
+	jsonFile := PathForOutput(ctx, "make_vars"+suffix+".json").String()
 
 	if ctx.Failed() {
 		return
@@ -100,19 +110,44 @@ func (s *makeVarsSingleton) GenerateBuildActions(ctx SingletonContext) {
 
 	outBytes := s.writeVars(vars)
 
-	if _, err := os.Stat(outFile); err == nil {
-		if data, err := ioutil.ReadFile(outFile); err == nil {
-			if bytes.Equal(data, outBytes) {
-				return
-			}
-		}
+	if err := writeFileIfChanged(outFile, outBytes); err != nil {
+		ctx.Errorf(err.Error())
+	}
+
+	jsonBytes, err := s.writeJsonVars(vars)
+	if err != nil {
+		ctx.Errorf(err.Error())
+		return
 	}
 
-	if err := ioutil.WriteFile(outFile, outBytes, 0666); err != nil {
+	if err := writeFileIfChanged(jsonFile, jsonBytes); err != nil {
 		ctx.Errorf(err.Error())
 	}
 }
 
+func (s *makeVarsSingleton) writeJsonVars(vars []makeVarsVariable) ([]byte, error) {
+	jsonVars := make([]makeVarsJsonVariable, 0, len(vars))
+	for _, v := range vars {
+		mode := "check"
+		if v.strict {
+			mode = "strict"
+		}
+		jsonVars = append(jsonVars, makeVarsJsonVariable{
+			Name:    v.name,
+			Value:   v.value,
+			Mode:    mode,
+			Sorted:  v.sort,
+			Package: v.pkg,
+		})
+	}
+
+	data, err := json.MarshalIndent(jsonVars, "", "    ")
+	if err != nil {
+		return nil, err
+	}
+	return append(data, '\n'), nil
+}
+
 func (s *makeVarsSingleton) writeVars(vars []makeVarsVariable) []byte {
 	buf := &bytes.Buffer{}
 
@@ -206,6 +241,7 @@ func (c *makeVarsContext) addVariableRaw(name, value string, strict, sort bool)
 		value:  value,
 		strict: strict,
 		sort:   sort,
+		pkg:    c.pctx.pkgPath,
 	})
 }
 
@@ -0,0 +1,182 @@
+package android
+
+import (
+	"encoding/json"
+	"math"
+	"sort"
+	"sync/atomic"
+	"time"
+
+	"github.com/google/blueprint"
+)
+
+const metricsFileName = "soong_metrics.json"
+
+type phaseMetrics struct {
+	begin int64
+	end   int64
+	total int64
+	calls int64
+}
+
+type moduleMetrics struct {
+	generateTime        time.Duration
+	buildCount          int
+	ruleCount           int
+	outputCount         int
+	estimatedNinjaBytes int
+}
+
+func (c *config) metricsPhase(name string) *phaseMetrics {
+	if phase, ok := c.metricsPhases.Load(name); ok {
+		return phase.(*phaseMetrics)
+	}
+	phase, _ := c.metricsPhases.LoadOrStore(name, &phaseMetrics{begin: math.MaxInt64, end: math.MinInt64})
+	return phase.(*phaseMetrics)
+}
+
+func (c *config) recordPhase(name string, begin, end time.Time) {
+	phase := c.metricsPhase(name)
+	beginNs, endNs := begin.UnixNano(), end.UnixNano()
+
+	for {
+		old := atomic.LoadInt64(&phase.begin)
+		if beginNs >= old || atomic.CompareAndSwapInt64(&phase.begin, old, beginNs) {
+			break
+		}
+	}
+	for {
+		old := atomic.LoadInt64(&phase.end)
+		if endNs <= old || atomic.CompareAndSwapInt64(&phase.end, old, endNs) {
+			break
+		}
+	}
+	atomic.AddInt64(&phase.total, endNs-beginNs)
+	atomic.AddInt64(&phase.calls, 1)
+}
+
+func (m *moduleMetrics) recordBuild(bparams blueprint.BuildParams) {
+	m.buildCount++
+	m.outputCount += len(bparams.Outputs) + len(bparams.ImplicitOutputs)
+	m.estimatedNinjaBytes += estimateNinjaBytes(bparams)
+}
+
+// estimateNinjaBytes approximates the size of the build statement that bparams will produce in
+// the ninja file. It doesn't account for variable expansion, escaping or line wrapping, so it is
+// only useful for comparing modules with each other.
+func estimateNinjaBytes(bparams blueprint.BuildParams) int {
+	size := len("build : \n")
+	if bparams.Rule != nil {
+		size += len(bparams.Rule.String())
+	}
+	for _, list := range [][]string{bparams.Outputs, bparams.ImplicitOutputs,
+		bparams.Inputs, bparams.Implicits, bparams.OrderOnly} {
+
+		size += len(" | ")
+		for _, s := range list {
+			size += len(s) + 1
+		}
+	}
+	if bparams.Description != "" {
+		size += len("    description = \n") + len(bparams.Description)
+	}
+	if bparams.Depfile != "" {
+		size += len("    depfile = \n") + len(bparams.Depfile)
+	}
+	for k, v := range bparams.Args {
+		size += len("     = \n") + len(k) + len(v)
+	}
+	return size
+}
+
+type phaseMetricsJson struct {
+	Name    string `json:"name"`
+	BeginUs int64  `json:"begin_us"`
+	EndUs   int64  `json:"end_us"`
+	TotalUs int64  `json:"total_us"`
+	Calls   int64  `json:"calls"`
+}
+
+type moduleMetricsJson struct {
+	Name                string `json:"name"`
+	Variant             string `json:"variant"`
+	Type                string `json:"type"`
+	GenerateUs          int64  `json:"generate_us"`
+	BuildCalls          int    `json:"build_calls"`
+	Rules               int    `json:"rules"`
+	Outputs             int    `json:"outputs"`
+	EstimatedNinjaBytes int    `json:"estimated_ninja_bytes"`
+}
+
+type metricsJson struct {
+	Phases  []phaseMetricsJson  `json:"phases"`
+	Modules []moduleMetricsJson `json:"modules"`
+}
+
+func toMicroseconds(d time.Duration) int64 {
+	return int64(d / time.Microsecond)
+}
+
+func MetricsSingleton() Singleton {
+	return &metricsSingleton{}
+}
+
+type metricsSingleton struct{}
+
+func (s *metricsSingleton) GenerateBuildActions(ctx SingletonContext) {
+	metrics := metricsJson{
+		Phases:  []phaseMetricsJson{},
+		Modules: []moduleMetricsJson{},
+	}
+
+	ctx.Config().metricsPhases.Range(func(key, value interface{}) bool {
+		phase := value.(*phaseMetrics)
+		metrics.Phases = append(metrics.Phases, phaseMetricsJson{
+			Name:    key.(string),
+			BeginUs: atomic.LoadInt64(&phase.begin) / int64(time.Microsecond),
+			EndUs:   atomic.LoadInt64(&phase.end) / int64(time.Microsecond),
+			TotalUs: toMicroseconds(time.Duration(atomic.LoadInt64(&phase.total))),
+			Calls:   atomic.LoadInt64(&phase.calls),
+		})
+		return true
+	})
+
+	sort.Slice(metrics.Phases, func(i, j int) bool {
+		if metrics.Phases[i].BeginUs != metrics.Phases[j].BeginUs {
+			return metrics.Phases[i].BeginUs < metrics.Phases[j].BeginUs
+		}
+		return metrics.Phases[i].Name < metrics.Phases[j].Name
+	})
+
+	ctx.VisitAllModules(func(module Module) {
+		m := module.base().metrics
+		metrics.Modules = append(metrics.Modules, moduleMetricsJson{
+			Name:                ctx.ModuleName(module),
+			Variant:             ctx.ModuleSubDir(module),
+			Type:                ctx.ModuleType(module),
+			GenerateUs:          toMicroseconds(m.generateTime),
+			BuildCalls:          m.buildCount,
+			Rules:               m.ruleCount,
+			Outputs:             m.outputCount,
+			EstimatedNinjaBytes: m.estimatedNinjaBytes,
+		})
+	})
+
+	sort.SliceStable(metrics.Modules, func(i, j int) bool {
+		if metrics.Modules[i].Name != metrics.Modules[j].Name {
+			return metrics.Modules[i].Name < metrics.Modules[j].Name
+		}
+		return metrics.Modules[i].Variant < metrics.Modules[j].Variant
+	})
+
+	data, err := json.MarshalIndent(&metrics, "", "  ")
+	if err != nil {
+		ctx.Errorf("%s", err.Error())
+		return
+	}
+
+	metricsFile := PathForOutput(ctx, metricsFileName).String()
+	if err := writeFileIfChanged(metricsFile, append(data, '\n')); err != nil {
+		ctx.Errorf("%s", err.Error())
+	}
+}
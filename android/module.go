@@ -6,6 +6,7 @@ import (
 	"sort"
 	"strings"
 	"text/scanner"
+	"time"
 
 	"github.com/google/blueprint"
 	"github.com/google/blueprint/pathtools"
@@ -55,6 +56,7 @@ type androidBaseContext interface {
 	DeviceSpecific() bool
 	SocSpecific() bool
 	ProductSpecific() bool
+	SystemExtSpecific() bool
 	AConfig() Config
 	DeviceConfig() DeviceConfig
 }
@@ -153,6 +155,8 @@ type commonProperties struct {
 		}
 	}
 
+	Native_bridge_supported *bool
+
 	Default_multilib        string `blueprint:"mutated"`
 	Proprietary             *bool
 	Owner                   *string
@@ -160,9 +164,15 @@ type commonProperties struct {
 	Soc_specific            *bool
 	Device_specific         *bool
 	Product_specific        *bool
+	System_ext_specific     *bool
+	Odm_specific            *bool
 	Init_rc                 []string
 	Vintf_fragments         []string
 	Required                []string `android:"arch_variant"`
+	Visibility              []string
+	Licenses                []string
+	Dist                    Dist
+	Dists                   []string
 	Notice                  *string
 	CompileTarget           Target                `blueprint:"mutated"`
 	CompilePrimary          bool                  `blueprint:"mutated"`
@@ -170,6 +180,7 @@ type commonProperties struct {
 	ArchSpecific            bool                  `blueprint:"mutated"`
 	SkipInstall             bool                  `blueprint:"mutated"`
 	NamespaceExportedToMake bool                  `blueprint:"mutated"`
+	NamespacePath           string                `blueprint:"mutated"`
 }
 
 type hostAndDeviceProperties struct {
@@ -206,6 +217,7 @@ const (
 	deviceSpecificModule
 	socSpecificModule
 	productSpecificModule
+	systemExtSpecificModule
 )
 
 func (k moduleKind) String() string {
@@ -218,6 +230,8 @@ func (k moduleKind) String() string {
 		return "soc-specific"
 	case productSpecificModule:
 		return "product-specific"
+	case systemExtSpecificModule:
+		return "system_ext-specific"
 	default:
 		panic(fmt.Errorf("unknown module kind %d", k))
 	}
@@ -268,6 +282,18 @@ type ModuleBase struct {
 	hooks                   hooks
 	registerProps           []interface{}
 	buildParams             []BuildParams
+	metrics                 moduleMetrics
+	directDeps              []moduleDependency
+	packageInfo             *packageInfo
+	namespace               *Namespace
+	licenses                []*licenseModule
+	distFiles               map[string]Paths
+	androidMkData           *AndroidMkData
+}
+
+type moduleDependency struct {
+	module blueprint.Module
+	tag    blueprint.DependencyTag
 }
 
 func (a *ModuleBase) AddProperties(props ...interface{}) {
@@ -354,11 +380,11 @@ func (a *ModuleBase) DeviceSupported() bool {
 }
 
 func (a *ModuleBase) Platform() bool {
-	return !a.DeviceSpecific() && !a.SocSpecific() && !a.ProductSpecific()
+	return !a.DeviceSpecific() && !a.SocSpecific() && !a.ProductSpecific() && !a.SystemExtSpecific()
 }
 
 func (a *ModuleBase) DeviceSpecific() bool {
-	return Bool(a.commonProperties.Device_specific)
+	return Bool(a.commonProperties.Device_specific) || Bool(a.commonProperties.Odm_specific)
 }
 
 func (a *ModuleBase) SocSpecific() bool {
@@ -369,6 +395,10 @@ func (a *ModuleBase) ProductSpecific() bool {
 	return Bool(a.commonProperties.Product_specific)
 }
 
+func (a *ModuleBase) SystemExtSpecific() bool {
+	return Bool(a.commonProperties.System_ext_specific)
+}
+
 func (a *ModuleBase) Enabled() bool {
 	if a.commonProperties.Enabled == nil {
 		return !a.Os().DefaultDisabled
@@ -472,8 +502,9 @@ func (a *ModuleBase) generateModuleTarget(ctx ModuleContext) {
 
 func determineModuleKind(a *ModuleBase, ctx blueprint.BaseModuleContext) moduleKind {
 	var socSpecific = Bool(a.commonProperties.Vendor) || Bool(a.commonProperties.Proprietary) || Bool(a.commonProperties.Soc_specific)
-	var deviceSpecific = Bool(a.commonProperties.Device_specific)
+	var deviceSpecific = Bool(a.commonProperties.Device_specific) || Bool(a.commonProperties.Odm_specific)
 	var productSpecific = Bool(a.commonProperties.Product_specific)
+	var systemExtSpecific = Bool(a.commonProperties.System_ext_specific)
 
 	if ((socSpecific || deviceSpecific) && productSpecific) || (socSpecific && deviceSpecific) {
 		msg := "conflicting value set here"
@@ -496,7 +527,13 @@ func determineModuleKind(a *ModuleBase, ctx blueprint.BaseModuleContext) moduleK
 		}
 	}
 
-	if productSpecific {
+	if systemExtSpecific && (socSpecific || deviceSpecific || productSpecific) {
+		ctx.PropertyErrorf("system_ext_specific", "a module cannot be specific to system_ext and SoC, device or product at the same time.")
+	}
+
+	if systemExtSpecific {
+		return systemExtSpecificModule
+	} else if productSpecific {
 		return productSpecificModule
 	} else if deviceSpecific {
 		return deviceSpecificModule
@@ -512,7 +549,7 @@ func (a *ModuleBase) androidBaseContextFactory(ctx blueprint.BaseModuleContext)
 		target:        a.commonProperties.CompileTarget,
 		targetPrimary: a.commonProperties.CompilePrimary,
 		kind:          determineModuleKind(a, ctx),
-		config:        ctx.Config().(Config),
+		config:        ctx.Config().(Config).withEnvReader(ctx.ModuleName()),
 	}
 }
 
@@ -524,7 +561,9 @@ func (a *ModuleBase) GenerateBuildActions(blueprintCtx blueprint.ModuleContext)
 		installDeps:            a.computeInstallDeps(blueprintCtx),
 		installFiles:           a.installFiles,
 		missingDeps:            blueprintCtx.GetMissingDependencies(),
+		metrics:                &a.metrics,
 	}
+	a.metrics = moduleMetrics{}
 
 	desc := "//" + ctx.ModuleDir() + ":" + ctx.ModuleName() + " "
 	var suffix []string
@@ -543,14 +582,26 @@ func (a *ModuleBase) GenerateBuildActions(blueprintCtx blueprint.ModuleContext)
 	}
 	ctx.Variable(pctx, "moduleDescSuffix", s)
 
+	a.directDeps = nil
+	blueprintCtx.VisitDirectDeps(func(dep blueprint.Module) {
+		a.directDeps = append(a.directDeps, moduleDependency{dep, blueprintCtx.OtherModuleDependencyTag(dep)})
+	})
+	a.computeLicenses(ctx)
+
 	if a.Enabled() {
+		begin := time.Now()
 		a.module.GenerateAndroidBuildActions(ctx)
+		end := time.Now()
+		a.metrics.generateTime = end.Sub(begin)
+		ctx.Config().recordPhase("generate_build_actions", begin, end)
 		if ctx.Failed() {
 			return
 		}
 
 		a.installFiles = append(a.installFiles, ctx.installFiles...)
 		a.checkbuildFiles = append(a.checkbuildFiles, ctx.checkbuildFiles...)
+
+		a.generateDistRules(ctx)
 	}
 
 	if a == ctx.FinalModule().(Module).base() {
@@ -580,6 +631,7 @@ type androidModuleContext struct {
 	missingDeps     []string
 	module          Module
 	buildParams     []BuildParams
+	metrics         *moduleMetrics
 }
 
 func (a *androidModuleContext) ninjaError(desc string, outputs []string, err error) {
@@ -596,7 +648,7 @@ func (a *androidModuleContext) ninjaError(desc string, outputs []string, err err
 }
 
 func (a *androidModuleContext) Config() Config {
-	return a.ModuleContext.Config().(Config)
+	return a.config
 }
 
 func (a *androidModuleContext) ModuleBuild(pctx PackageContext, params ModuleBuildParams) {
@@ -643,6 +695,7 @@ func (a *androidModuleContext) Variable(pctx PackageContext, name, value string)
 func (a *androidModuleContext) Rule(pctx PackageContext, name string, params blueprint.RuleParams,
 	argNames ...string) blueprint.Rule {
 
+	a.metrics.ruleCount++
 	return a.ModuleContext.Rule(pctx.PackageContext, name, params, argNames...)
 }
 
@@ -657,6 +710,8 @@ func (a *androidModuleContext) Build(pctx PackageContext, params BuildParams) {
 		bparams.Description = "${moduleDesc}" + params.Description + "${moduleDescSuffix}"
 	}
 
+	a.metrics.recordBuild(bparams)
+
 	if a.missingDeps != nil {
 		a.ninjaError(bparams.Description, bparams.Outputs,
 			fmt.Errorf("module %s missing dependencies: %s\n", a.ModuleName(), strings.Join(a.missingDeps, ", ")))
@@ -847,6 +902,10 @@ func (a *androidBaseContextImpl) ProductSpecific() bool {
 	return a.kind == productSpecificModule
 }
 
+func (a *androidBaseContextImpl) SystemExtSpecific() bool {
+	return a.kind == systemExtSpecificModule
+}
+
 func (a *androidModuleContext) InstallInData() bool {
 	return a.module.InstallInData()
 }
@@ -0,0 +1,174 @@
+package android
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"sort"
+
+	"github.com/google/blueprint"
+)
+
+func init() {
+	RegisterSingletonType("module_graph", ModuleGraphSingleton)
+}
+
+func ModuleGraphSingleton() Singleton {
+	return &moduleGraphSingleton{}
+}
+
+type moduleGraphSingleton struct{}
+
+type moduleGraphTarget struct {
+	Os           string `json:"os"`
+	Class        string `json:"class"`
+	Arch         string `json:"arch,omitempty"`
+	Arch_variant string `json:"arch_variant,omitempty"`
+	Cpu_variant  string `json:"cpu_variant,omitempty"`
+}
+
+type moduleGraphDep struct {
+	Name    string `json:"name"`
+	Variant string `json:"variant"`
+	Tag     string `json:"tag"`
+	Kind    string `json:"kind,omitempty"`
+}
+
+type moduleGraphNode struct {
+	Name      string            `json:"name"`
+	Variant   string            `json:"variant"`
+	Type      string            `json:"type"`
+	Blueprint string            `json:"blueprint"`
+	Namespace string            `json:"namespace"`
+	Enabled   bool              `json:"enabled"`
+	Target    moduleGraphTarget `json:"target"`
+	Deps      []moduleGraphDep  `json:"deps"`
+}
+
+func moduleGraphId(ctx SingletonContext, module blueprint.Module) string {
+	if variant := ctx.ModuleSubDir(module); variant != "" {
+		return ctx.ModuleName(module) + " (" + variant + ")"
+	}
+	return ctx.ModuleName(module)
+}
+
+func (s *moduleGraphSingleton) GenerateBuildActions(ctx SingletonContext) {
+	var nodes []moduleGraphNode
+	var modules []Module
+
+	ctx.VisitAllModules(func(module Module) {
+		base := module.base()
+		target := base.Target()
+
+		node := moduleGraphNode{
+			Name:      ctx.ModuleName(module),
+			Variant:   ctx.ModuleSubDir(module),
+			Type:      ctx.ModuleType(module),
+			Blueprint: ctx.BlueprintFile(module),
+			Namespace: base.commonProperties.NamespacePath,
+			Enabled:   base.Enabled(),
+			Target: moduleGraphTarget{
+				Os:           target.Os.String(),
+				Class:        target.Os.Class.String(),
+				Arch:         target.Arch.ArchType.String(),
+				Arch_variant: target.Arch.ArchVariant,
+				Cpu_variant:  target.Arch.CpuVariant,
+			},
+			Deps: []moduleGraphDep{},
+		}
+
+		for _, dep := range base.directDeps {
+			node.Deps = append(node.Deps, moduleGraphDep{
+				Name:    ctx.ModuleName(dep.module),
+				Variant: ctx.ModuleSubDir(dep.module),
+				Tag:     fmt.Sprintf("%T", dep.tag),
+				Kind:    dependencyKind(dep.tag),
+			})
+		}
+
+		nodes = append(nodes, node)
+		modules = append(modules, module)
+	})
+
+	sort.SliceStable(nodes, func(i, j int) bool {
+		if nodes[i].Name != nodes[j].Name {
+			return nodes[i].Name < nodes[j].Name
+		}
+		return nodes[i].Variant < nodes[j].Variant
+	})
+
+	data, err := json.MarshalIndent(nodes, "", "  ")
+	if err != nil {
+		ctx.Errorf("failed to marshal module graph: %s", err.Error())
+		return
+	}
+
+	if err := writeFileIfChanged(PathForOutput(ctx, "module_graph.json").String(), append(data, '\n')); err != nil {
+		ctx.Errorf("%s", err.Error())
+	}
+
+	if root := ctx.Config().Getenv("SOONG_MODULE_GRAPH_DOT"); root != "" {
+		s.writeDot(ctx, root, modules)
+	}
+}
+
+func (s *moduleGraphSingleton) writeDot(ctx SingletonContext, root string, modules []Module) {
+	var queue []blueprint.Module
+	for _, module := range modules {
+		if ctx.ModuleName(module) == root {
+			queue = append(queue, module)
+		}
+	}
+
+	if len(queue) == 0 {
+		ctx.Errorf("SOONG_MODULE_GRAPH_DOT: module %q does not exist", root)
+		return
+	}
+
+	visited := make(map[blueprint.Module]bool)
+	var edges []string
+	for len(queue) > 0 {
+		module := queue[0]
+		queue = queue[1:]
+		if visited[module] {
+			continue
+		}
+		visited[module] = true
+
+		m, ok := module.(Module)
+		if !ok {
+			continue
+		}
+
+		for _, dep := range m.base().directDeps {
+			label := dependencyKind(dep.tag)
+			if label == "" {
+				label = fmt.Sprintf("%T", dep.tag)
+			}
+			edges = append(edges, fmt.Sprintf("  %q -> %q [label=%q];",
+				moduleGraphId(ctx, module), moduleGraphId(ctx, dep.module), label))
+			queue = append(queue, dep.module)
+		}
+	}
+
+	var nodes []string
+	for module := range visited {
+		nodes = append(nodes, fmt.Sprintf("  %q;", moduleGraphId(ctx, module)))
+	}
+	sort.Strings(nodes)
+	sort.Strings(edges)
+
+	buf := &bytes.Buffer{}
+	fmt.Fprintln(buf, "digraph module_graph {")
+	for _, node := range nodes {
+		fmt.Fprintln(buf, node)
+	}
+	for _, edge := range edges {
+		fmt.Fprintln(buf, edge)
+	}
+	fmt.Fprintln(buf, "}")
+
+	if err := writeFileIfChanged(PathForOutput(ctx, "module_graph.dot").String(), buf.Bytes()); err != nil {
+		ctx.Errorf("%s", err.Error())
+	}
+}
@@ -0,0 +1,120 @@
+package android
+
+import (
+	"encoding/json"
+	"sort"
+)
+
+func init() {
+	RegisterSingletonType("module_info", ModuleInfoSingleton)
+}
+
+type TestSuiteModule interface {
+	TestSuites() []string
+}
+
+func ModuleInfoSingleton() Singleton {
+	return &moduleInfoSingleton{}
+}
+
+type moduleInfoSingleton struct{}
+
+type moduleInfoEntry struct {
+	Class                []string `json:"class"`
+	Path                 []string `json:"path"`
+	Tags                 []string `json:"tags"`
+	Installed            []string `json:"installed"`
+	Compatibility_suites []string `json:"compatibility_suites"`
+	Required             []string `json:"required"`
+	Dependencies         []string `json:"dependencies"`
+	Module_name          string   `json:"module_name"`
+}
+
+func (s *moduleInfoSingleton) GenerateBuildActions(ctx SingletonContext) {
+	entries := make(map[string]*moduleInfoEntry)
+
+	ctx.VisitAllModules(func(module Module) {
+		base := module.base()
+		if !base.Enabled() {
+			return
+		}
+
+		name := ctx.ModuleName(module)
+		var class string
+		var required []string
+		if data, ok := androidMkDataForModule(module); ok {
+			if data.Disabled {
+				return
+			}
+			name = module.(AndroidMkDataProvider).BaseModuleName() + data.SubName
+			class = data.Class
+			required = data.Required
+		}
+
+		entry := entries[name]
+		if entry == nil {
+			entry = &moduleInfoEntry{Module_name: name}
+			entries[name] = entry
+		}
+
+		if class != "" {
+			entry.Class = append(entry.Class, class)
+		}
+		entry.Path = append(entry.Path, ctx.ModuleDir(module))
+
+		tags := base.commonProperties.Tags
+		if len(tags) == 0 {
+			tags = []string{"optional"}
+		}
+		entry.Tags = append(entry.Tags, tags...)
+
+		for _, installed := range base.filesToInstall() {
+			entry.Installed = append(entry.Installed, installed.String())
+		}
+
+		if test, ok := module.(TestSuiteModule); ok {
+			entry.Compatibility_suites = append(entry.Compatibility_suites, test.TestSuites()...)
+		}
+
+		entry.Required = append(entry.Required, required...)
+		entry.Required = append(entry.Required, base.commonProperties.Required...)
+
+		for _, dep := range base.directDeps {
+			if _, ok := dep.module.(Module); !ok {
+				continue
+			}
+			if depName := ctx.ModuleName(dep.module); depName != name {
+				entry.Dependencies = append(entry.Dependencies, depName)
+			}
+		}
+	})
+
+	for _, entry := range entries {
+		entry.Class = moduleInfoList(entry.Class)
+		entry.Path = moduleInfoList(entry.Path)
+		entry.Tags = moduleInfoList(entry.Tags)
+		entry.Installed = moduleInfoList(entry.Installed)
+		entry.Compatibility_suites = moduleInfoList(entry.Compatibility_suites)
+		entry.Required = moduleInfoList(entry.Required)
+		entry.Dependencies = moduleInfoList(entry.Dependencies)
+	}
+
+	data, err := json.MarshalIndent(entries, "", "  ")
+	if err != nil {
+		ctx.Errorf("failed to marshal module-info: %s", err.Error())
+		return
+	}
+
+	if err := writeFileIfChanged(PathForOutput(ctx, "module-info.json").String(), append(data, '\n')); err != nil {
+		ctx.Errorf("%s", err.Error())
+	}
+}
+
+func moduleInfoList(list []string) []string {
+	list = FirstUniqueStrings(list)
+	sort.Strings(list)
+	if list == nil {
+		list = []string{}
+	}
+	return list
+}
@@ -1,6 +1,8 @@
 package android
 
 import (
+	"time"
+
 	"github.com/google/blueprint"
 	"github.com/google/blueprint/proptools"
 )
@@ -32,6 +34,7 @@ func registerMutators(ctx *blueprint.Context, preArch, preDeps, postDeps []Regis
 
 	register(preDeps)
 
+	mctx.BottomUp("exclusions", exclusionsMutator).Parallel()
 	mctx.BottomUp("deps", depsMutator).Parallel()
 
 	register(postDeps)
@@ -57,6 +60,7 @@ var preArch = []RegisterMutatorFunc{
 	RegisterNamespaceMutator,
 	RegisterPrebuiltsPreArchMutators,
 	RegisterDefaultsPreArchMutators,
+	registerLicensesPreArchMutator,
 }
 
 func registerArchMutator(ctx RegisterMutatorsContext) {
@@ -71,6 +75,7 @@ var preDeps = []RegisterMutatorFunc{
 var postDeps = []RegisterMutatorFunc{
 	RegisterPrebuiltsPostDepsMutators,
 	registerNeverallowMutator,
+	registerVisibilityMutator,
 }
 
 func PreArchMutators(f RegisterMutatorFunc) {
@@ -96,6 +101,7 @@ type TopDownMutatorContext interface {
 	Module() Module
 
 	OtherModuleName(m blueprint.Module) string
+	OtherModuleDir(m blueprint.Module) string
 	OtherModuleErrorf(m blueprint.Module, fmt string, args ...interface{})
 	OtherModuleDependencyTag(m blueprint.Module) blueprint.DependencyTag
 
@@ -144,13 +150,16 @@ type androidBottomUpMutatorContext struct {
 }
 
 func (x *registerMutatorsContext) BottomUp(name string, m AndroidBottomUpMutator) MutatorHandle {
+	phase := "mutator:" + name
 	f := func(ctx blueprint.BottomUpMutatorContext) {
 		if a, ok := ctx.Module().(Module); ok {
 			actx := &androidBottomUpMutatorContext{
 				BottomUpMutatorContext: ctx,
 				androidBaseContextImpl: a.base().androidBaseContextFactory(ctx),
 			}
+			begin := time.Now()
 			m(actx)
+			actx.Config().recordPhase(phase, begin, time.Now())
 		}
 	}
 	mutator := &mutator{name: name, bottomUpMutator: f}
@@ -159,13 +168,16 @@ func (x *registerMutatorsContext) BottomUp(name string, m AndroidBottomUpMutator
 }
 
 func (x *registerMutatorsContext) TopDown(name string, m AndroidTopDownMutator) MutatorHandle {
+	phase := "mutator:" + name
 	f := func(ctx blueprint.TopDownMutatorContext) {
 		if a, ok := ctx.Module().(Module); ok {
 			actx := &androidTopDownMutatorContext{
 				TopDownMutatorContext:  ctx,
 				androidBaseContextImpl: a.base().androidBaseContextFactory(ctx),
 			}
+			begin := time.Now()
 			m(actx)
+			actx.Config().recordPhase(phase, begin, time.Now())
 		}
 	}
 	mutator := &mutator{name: name, topDownMutator: f}
@@ -184,6 +196,7 @@ func (mutator *mutator) Parallel() MutatorHandle {
 
 func depsMutator(ctx BottomUpMutatorContext) {
 	if m, ok := ctx.Module().(Module); ok {
+		addLicensesDeps(ctx, m)
 		m.DepsMutator(ctx)
 	}
 }
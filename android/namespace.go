@@ -63,6 +63,7 @@ type NameResolver struct {
 	nextNamespaceId       int32
 	sortedNamespaces      sortedNamespaces
 	namespacesByDir       sync.Map
+	packagesByDir         sync.Map
 	namespaceExportFilter func(*Namespace) bool
 }
 
@@ -125,6 +126,11 @@ func (r *NameResolver) namespaceAt(path string) (namespace *Namespace, found boo
 	return mapVal.(*Namespace), true
 }
 
+func (r *NameResolver) packageAt(dir string) *packageInfo {
+	mapVal, _ := r.packagesByDir.LoadOrStore(dir, &packageInfo{})
+	return mapVal.(*packageInfo)
+}
+
 func (r *NameResolver) findNamespace(path string) (namespace *Namespace) {
 	namespace, found := r.namespaceAt(path)
 	if found {
@@ -149,6 +155,13 @@ func (r *NameResolver) NewModule(ctx blueprint.NamespaceContext, moduleGroup blu
 		return nil, nil
 	}
 
+	if pkg, ok := module.(*packageModule); ok {
+		if err := r.packageAt(filepath.Dir(ctx.ModulePath())).setModule(pkg); err != nil {
+			return nil, []error{err}
+		}
+		return nil, nil
+	}
+
 	ns := r.findNamespaceFromCtx(ctx)
 
 	_, errs = ns.moduleContainer.NewModule(ctx, moduleGroup, module)
@@ -159,6 +172,9 @@ func (r *NameResolver) NewModule(ctx blueprint.NamespaceContext, moduleGroup blu
 	amod, ok := module.(Module)
 	if ok {
 		amod.base().commonProperties.NamespaceExportedToMake = ns.exportToKati
+		amod.base().commonProperties.NamespacePath = ns.Path
+		amod.base().namespace = ns
+		amod.base().packageInfo = r.packageAt(filepath.Dir(ctx.ModulePath()))
 	}
 
 	return ns, nil
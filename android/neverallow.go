@@ -1,16 +1,27 @@
 package android
 
 import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"os"
 	"path/filepath"
 	"reflect"
 	"strconv"
 	"strings"
 
+	"github.com/google/blueprint"
 	"github.com/google/blueprint/proptools"
 )
 
+func init() {
+	RegisterSingletonType("neverallow_files", NeverallowFilesSingleton)
+}
+
 func registerNeverallowMutator(ctx RegisterMutatorsContext) {
 	ctx.BottomUp("neverallow", neverallowMutator).Parallel()
+	ctx.TopDown("neverallow_deps", neverallowDepsMutator).Parallel()
 }
 
 var neverallows = []*rule{
@@ -35,6 +46,43 @@ var neverallows = []*rule{
 		without("name", "libc_bionic_ndk").
 		with("product_variables.treble_linker_namespaces.cflags", "*").
 		because("nothing should care if linker namespaces are enabled or not"),
+
+	neverallow().
+		with("system_ext_specific", "true").
+		with("vendor", "true").
+		because("a module cannot be installed on both the system_ext and vendor partitions."),
+	neverallow().
+		with("system_ext_specific", "true").
+		with("proprietary", "true").
+		because("a module cannot be installed on both the system_ext and vendor partitions."),
+	neverallow().
+		with("system_ext_specific", "true").
+		with("soc_specific", "true").
+		because("a module cannot be installed on both the system_ext and vendor partitions."),
+	neverallow().
+		with("system_ext_specific", "true").
+		with("device_specific", "true").
+		because("a module cannot be installed on both the system_ext and odm partitions."),
+	neverallow().
+		with("system_ext_specific", "true").
+		with("odm_specific", "true").
+		because("a module cannot be installed on both the system_ext and odm partitions."),
+	neverallow().
+		with("system_ext_specific", "true").
+		with("product_specific", "true").
+		because("a module cannot be installed on both the system_ext and product partitions."),
+	neverallow().
+		with("odm_specific", "true").
+		with("vendor", "true").
+		because("a module cannot be installed on both the odm and vendor partitions."),
+	neverallow().
+		with("odm_specific", "true").
+		with("soc_specific", "true").
+		because("a module cannot be installed on both the odm and vendor partitions."),
+	neverallow().
+		with("odm_specific", "true").
+		with("product_specific", "true").
+		because("a module cannot be installed on both the odm and product partitions."),
 }
 
 func neverallowMutator(ctx BottomUpMutatorContext) {
@@ -46,30 +94,111 @@ func neverallowMutator(ctx BottomUpMutatorContext) {
 	dir := ctx.ModuleDir() + "/"
 	properties := m.GetProperties()
 
-	for _, n := range neverallows {
-		if !n.appliesToPath(dir) {
-			continue
-		}
+	for _, rules := range [][]*rule{neverallows, ctx.Config().neverallowRules} {
+		for _, n := range rules {
+			if n.isDepRule() {
+				continue
+			}
 
-		if !n.appliesToProperties(properties) {
-			continue
+			if !n.appliesToPath(dir) {
+				continue
+			}
+
+			if !n.appliesToProperties(properties) {
+				continue
+			}
+
+			ctx.ModuleErrorf("violates " + n.String())
 		}
+	}
+}
+
+type DependencyKindTag interface {
+	blueprint.DependencyTag
+	DependencyKind() string
+}
+
+func dependencyKind(tag blueprint.DependencyTag) string {
+	if t, ok := tag.(DependencyKindTag); ok {
+		return t.DependencyKind()
+	}
+	return ""
+}
+
+func neverallowDepsMutator(ctx TopDownMutatorContext) {
+	m := ctx.Module()
+	if m == nil {
+		return
+	}
+
+	dir := ctx.ModuleDir() + "/"
+	properties := m.GetProperties()
+
+	for _, rules := range [][]*rule{neverallows, ctx.Config().neverallowRules} {
+		for _, n := range rules {
+			if !n.isDepRule() {
+				continue
+			}
+
+			if !n.appliesToPath(dir) {
+				continue
+			}
+
+			if !n.appliesToProperties(properties) {
+				continue
+			}
 
-		ctx.ModuleErrorf("violates " + n.String())
+			checkNeverallowDeps(ctx, n)
+		}
 	}
 }
 
+func checkNeverallowDeps(ctx TopDownMutatorContext, n *rule) {
+	paths := map[Module]string{ctx.Module(): ctx.ModuleName()}
+	reported := make(map[Module]bool)
+
+	ctx.WalkDeps(func(child, parent Module) bool {
+		kind := dependencyKind(ctx.OtherModuleDependencyTag(child))
+		if !n.appliesToEdge(kind) {
+			return false
+		}
+
+		path, seen := paths[child]
+		if !seen {
+			label := kind
+			if label == "" {
+				label = "dep"
+			}
+			path = paths[parent] + " -> (" + label + ") " + ctx.OtherModuleName(child)
+			paths[child] = path
+		}
+
+		if !reported[child] && n.appliesToDep(ctx.OtherModuleName(child), ctx.OtherModuleDir(child)+"/", child.GetProperties()) {
+			reported[child] = true
+			ctx.ModuleErrorf("violates %s: dependency path %s", n.String(), path)
+		}
+
+		return n.transitive && !seen
+	})
+}
+
 type ruleProperty struct {
 	fields []string // e.x.: Vndk.Enabled
 	value  string   // e.x.: true
 }
 
 type rule struct {
+	source      string
 	reason      string
 	paths       []string
 	unlessPaths []string
 	props       []ruleProperty
 	unlessProps []ruleProperty
+	depNames    []string
+	depPaths    []string
+	depKinds    []string
+	depProps    []ruleProperty
+	transitive  bool
 }
 
 func neverallow() *rule {
@@ -97,6 +226,29 @@ func (r *rule) without(properties, value string) *rule {
 	})
 	return r
 }
+func (r *rule) dependingOn(names ...string) *rule {
+	r.depNames = append(r.depNames, names...)
+	return r
+}
+func (r *rule) dependingOnIn(path ...string) *rule {
+	r.depPaths = append(r.depPaths, cleanPaths(path)...)
+	return r
+}
+func (r *rule) withDepKind(kinds ...string) *rule {
+	r.depKinds = append(r.depKinds, kinds...)
+	return r
+}
+func (r *rule) withDepProperty(properties, value string) *rule {
+	r.depProps = append(r.depProps, ruleProperty{
+		fields: fieldNamesForProperties(properties),
+		value:  value,
+	})
+	return r
+}
+func (r *rule) transitively() *rule {
+	r.transitive = true
+	return r
+}
 func (r *rule) because(reason string) *rule {
 	r.reason = reason
 	return r
@@ -116,9 +268,27 @@ func (r *rule) String() string {
 	for _, v := range r.unlessProps {
 		s += " -" + strings.Join(v.fields, ".") + "=" + v.value
 	}
+	for _, v := range r.depNames {
+		s += " dep:" + v
+	}
+	for _, v := range r.depPaths {
+		s += " depdir:" + v + "*"
+	}
+	for _, v := range r.depKinds {
+		s += " depkind:" + v
+	}
+	for _, v := range r.depProps {
+		s += " dep." + strings.Join(v.fields, ".") + "=" + v.value
+	}
+	if r.transitive {
+		s += " transitive"
+	}
 	if len(r.reason) != 0 {
 		s += " which is restricted because " + r.reason
 	}
+	if len(r.source) != 0 {
+		s += " (rule defined at " + r.source + ")"
+	}
 	return s
 }
 
@@ -134,6 +304,177 @@ func (r *rule) appliesToProperties(properties []interface{}) bool {
 	return includeProps && !excludeProps
 }
 
+func (r *rule) isDepRule() bool {
+	return len(r.depNames) > 0 || len(r.depPaths) > 0 || len(r.depKinds) > 0 || len(r.depProps) > 0
+}
+
+func (r *rule) appliesToEdge(kind string) bool {
+	return len(r.depKinds) == 0 || InList(kind, r.depKinds)
+}
+
+func (r *rule) appliesToDep(name, dir string, properties []interface{}) bool {
+	includeName := len(r.depNames) == 0 || InList(name, r.depNames)
+	includePath := len(r.depPaths) == 0 || hasAnyPrefix(dir, r.depPaths)
+	includeProps := hasAllProperties(properties, r.depProps)
+	return includeName && includePath && includeProps
+}
+
+type neverallowJsonProperty struct {
+	Property string
+	Value    string
+}
+
+type neverallowJsonRule struct {
+	In            []string
+	Not_in        []string
+	With          []neverallowJsonProperty
+	Without       []neverallowJsonProperty
+	Depends_on    []string
+	Depends_on_in []string
+	Dep_kinds     []string
+	Dep_with      []neverallowJsonProperty
+	Transitive    bool
+	Because       string
+}
+
+func (j *neverallowJsonRule) toRule(source string) (*rule, error) {
+	if len(j.In) == 0 && len(j.Not_in) == 0 && len(j.With) == 0 && len(j.Without) == 0 &&
+		len(j.Depends_on) == 0 && len(j.Depends_on_in) == 0 && len(j.Dep_kinds) == 0 && len(j.Dep_with) == 0 {
+		return nil, fmt.Errorf("rule must set at least one of in, not_in, with, without, depends_on, depends_on_in, dep_kinds or dep_with")
+	}
+
+	r := neverallow()
+	r.source = source
+	if len(j.In) > 0 {
+		r.in(j.In...)
+	}
+	if len(j.Not_in) > 0 {
+		r.notIn(j.Not_in...)
+	}
+	for _, p := range j.With {
+		if p.Property == "" {
+			return nil, fmt.Errorf("with entry is missing a property name")
+		}
+		r.with(p.Property, p.Value)
+	}
+	for _, p := range j.Without {
+		if p.Property == "" {
+			return nil, fmt.Errorf("without entry is missing a property name")
+		}
+		r.without(p.Property, p.Value)
+	}
+	if len(j.Depends_on) > 0 {
+		r.dependingOn(j.Depends_on...)
+	}
+	if len(j.Depends_on_in) > 0 {
+		r.dependingOnIn(j.Depends_on_in...)
+	}
+	if len(j.Dep_kinds) > 0 {
+		r.withDepKind(j.Dep_kinds...)
+	}
+	for _, p := range j.Dep_with {
+		if p.Property == "" {
+			return nil, fmt.Errorf("dep_with entry is missing a property name")
+		}
+		r.withDepProperty(p.Property, p.Value)
+	}
+	if j.Transitive {
+		if !r.isDepRule() {
+			return nil, fmt.Errorf("transitive is only valid on rules with dependency predicates")
+		}
+		r.transitively()
+	}
+	r.because(j.Because)
+
+	return r, nil
+}
+
+func loadNeverallowRules(c *config) error {
+	listFile := c.Getenv("SOONG_NEVERALLOW_LIST_FILE")
+	if listFile == "" {
+		return nil
+	}
+
+	data, err := ioutil.ReadFile(listFile)
+	if os.IsNotExist(err) {
+		return nil
+	} else if err != nil {
+		return fmt.Errorf("neverallow: could not read %s: %s", listFile, err.Error())
+	}
+
+	c.neverallowFiles = append(c.neverallowFiles, listFile)
+
+	for _, file := range strings.Split(string(data), "\n") {
+		file = strings.TrimSpace(file)
+		if file == "" {
+			continue
+		}
+
+		rules, err := loadNeverallowFile(file)
+		if err != nil {
+			return err
+		}
+
+		c.neverallowRules = append(c.neverallowRules, rules...)
+		c.neverallowFiles = append(c.neverallowFiles, file)
+	}
+
+	return nil
+}
+
+func loadNeverallowFile(filename string) ([]*rule, error) {
+	data, err := ioutil.ReadFile(filename)
+	if err != nil {
+		return nil, fmt.Errorf("neverallow: could not read %s: %s", filename, err.Error())
+	}
+
+	decoder := json.NewDecoder(bytes.NewReader(data))
+	decoder.DisallowUnknownFields()
+
+	if tok, err := decoder.Token(); err != nil || tok != json.Delim('[') {
+		return nil, fmt.Errorf("%s:1: expected a list of neverallow rules", filename)
+	}
+
+	var rules []*rule
+	for decoder.More() {
+		source := fmt.Sprintf("%s:%d", filename, lineForOffset(data, decoder.InputOffset()))
+
+		var j neverallowJsonRule
+		if err := decoder.Decode(&j); err != nil {
+			return nil, fmt.Errorf("%s: %s", source, err.Error())
+		}
+
+		r, err := j.toRule(source)
+		if err != nil {
+			return nil, fmt.Errorf("%s: %s", source, err.Error())
+		}
+		rules = append(rules, r)
+	}
+
+	if _, err := decoder.Token(); err != nil {
+		return nil, fmt.Errorf("%s: %s", filename, err.Error())
+	}
+
+	return rules, nil
+}
+
+func lineForOffset(data []byte, offset int64) int {
+	for offset < int64(len(data)) && strings.IndexByte(" \t\r\n,", data[offset]) != -1 {
+		offset++
+	}
+	return bytes.Count(data[:offset], []byte("\n")) + 1
+}
+
+func NeverallowFilesSingleton() Singleton {
+	return &neverallowFilesSingleton{}
+}
+
+type neverallowFilesSingleton struct{}
+
+func (s *neverallowFilesSingleton) GenerateBuildActions(ctx SingletonContext) {
+	ctx.AddNinjaFileDeps(ctx.Config().neverallowFiles...)
+}
+
 func cleanPaths(paths []string) []string {
 	res := make([]string, len(paths))
 	for i, v := range paths {
@@ -0,0 +1,274 @@
+package android
+
+import (
+	"bytes"
+	"crypto/sha1"
+	"encoding/hex"
+	"fmt"
+	"html"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"github.com/google/blueprint"
+)
+
+func init() {
+	RegisterSingletonType("notice", NoticeSingleton)
+}
+
+var (
+	sedCmd  = pctx.HostToolVariable("sedCmd", "sed")
+	gzipCmd = pctx.HostToolVariable("gzipCmd", "gzip")
+
+	noticeHtmlEscape = pctx.AndroidStaticRule("noticeHtmlEscape",
+		blueprint.RuleParams{
+			Command:     `$sedCmd -e 's/&/\&amp;/g' -e 's/</\&lt;/g' -e 's/>/\&gt;/g' $in > $out`,
+			Description: "escape notice $out",
+		})
+
+	noticeXmlEscape = pctx.AndroidStaticRule("noticeXmlEscape",
+		blueprint.RuleParams{
+			Command:     `$sedCmd -e 's/]]>/]]]]><![CDATA[>/g' $in > $out`,
+			Description: "escape notice $out",
+		})
+
+	noticeGzip = pctx.AndroidStaticRule("noticeGzip",
+		blueprint.RuleParams{
+			Command:     "$gzipCmd -c -n $in > $out",
+			Description: "gzip $out",
+		})
+)
+
+func NoticeSingleton() Singleton {
+	return &noticeSingleton{}
+}
+
+type noticeSingleton struct{}
+
+type noticeText struct {
+	id    string
+	path  Path
+	files []string
+}
+
+type noticePartition struct {
+	texts map[string]*noticeText
+}
+
+func (s *noticeSingleton) GenerateBuildActions(ctx SingletonContext) {
+	productOut := PathForOutput(ctx, "target", "product", ctx.Config().DeviceName()).String()
+	partitions := make(map[string]*noticePartition)
+
+	ctx.VisitAllModules(func(module Module) {
+		base := module.base()
+		if !base.Enabled() || base.commonProperties.SkipInstall {
+			return
+		}
+
+		texts := base.LicenseTexts()
+		if notice := base.commonProperties.Notice; notice != nil {
+			texts = append(texts, PathForSource(ctx, ctx.ModuleDir(module), *notice))
+		}
+		if len(texts) == 0 {
+			return
+		}
+
+		for _, installed := range base.filesToInstall() {
+			rel, err := filepath.Rel(productOut, installed.String())
+			if err != nil || strings.HasPrefix(rel, "..") {
+				continue
+			}
+			partition := strings.SplitN(rel, string(filepath.Separator), 2)[0]
+
+			p := partitions[partition]
+			if p == nil {
+				p = &noticePartition{texts: make(map[string]*noticeText)}
+				partitions[partition] = p
+			}
+
+			for _, path := range texts {
+				sum := sha1.Sum([]byte(path.String()))
+				id := hex.EncodeToString(sum[:])
+				if p.texts[id] == nil {
+					p.texts[id] = &noticeText{id: id, path: path}
+				}
+				p.texts[id].files = append(p.texts[id].files, "/"+filepath.ToSlash(rel))
+			}
+		}
+	})
+
+	var names []string
+	for partition := range partitions {
+		names = append(names, partition)
+	}
+	sort.Strings(names)
+
+	escaped := make(map[string]Paths)
+	var outputs Paths
+	for _, partition := range names {
+		p := partitions[partition]
+		var texts []*noticeText
+		for _, text := range p.texts {
+			text.files = FirstUniqueStrings(text.files)
+			sort.Strings(text.files)
+			texts = append(texts, text)
+		}
+		sort.Slice(texts, func(i, j int) bool {
+			if texts[i].files[0] != texts[j].files[0] {
+				return texts[i].files[0] < texts[j].files[0]
+			}
+			return texts[i].id < texts[j].id
+		})
+
+		for _, text := range texts {
+			if escaped[text.id] != nil {
+				continue
+			}
+			htmlText := PathForOutput(ctx, "notice", "texts", text.id+".html")
+			ctx.Build(pctx, BuildParams{
+				Rule:   noticeHtmlEscape,
+				Input:  text.path,
+				Output: htmlText,
+			})
+			xmlText := PathForOutput(ctx, "notice", "texts", text.id+".xml")
+			ctx.Build(pctx, BuildParams{
+				Rule:   noticeXmlEscape,
+				Input:  text.path,
+				Output: xmlText,
+			})
+			escaped[text.id] = Paths{htmlText, xmlText}
+		}
+
+		htmlFile := PathForOutput(ctx, "notice", partition, "NOTICE.html")
+		buildNoticeFile(ctx, htmlFile, noticeHtml(ctx, partition, texts, escaped))
+
+		xmlFile := PathForOutput(ctx, "notice", partition, "NOTICE.xml")
+		buildNoticeFile(ctx, xmlFile, noticeXml(ctx, partition, texts, escaped))
+
+		xmlGzFile := PathForOutput(ctx, "notice", partition, "NOTICE.xml.gz")
+		ctx.Build(pctx, BuildParams{
+			Rule:    noticeGzip,
+			Input:   xmlFile,
+			Output:  xmlGzFile,
+			Default: true,
+		})
+
+		outputs = append(outputs, htmlFile, xmlGzFile)
+	}
+
+	if len(outputs) > 0 {
+		ctx.Build(pctx, BuildParams{
+			Rule:      blueprint.Phony,
+			Output:    PathForPhony(ctx, "notice_files"),
+			Implicits: outputs,
+		})
+	}
+}
+
+func buildNoticeFile(ctx SingletonContext, out WritablePath, parts Paths) {
+	ctx.Build(pctx, BuildParams{
+		Rule:    Cat,
+		Inputs:  parts,
+		Output:  out,
+		Default: true,
+	})
+}
+
+func noticeFragment(ctx SingletonContext, partition, name string, buf *bytes.Buffer) Path {
+	content := strings.TrimSuffix(buf.String(), "\n")
+	content = strings.Replace(content, "\\", "\\\\", -1)
+	content = strings.Replace(content, "'", "'\\''", -1)
+	content = strings.Replace(content, "\n", "\\n", -1)
+	content = strings.Replace(content, "$", "$$", -1)
+
+	path := PathForOutput(ctx, "notice", partition, "fragments", name)
+	ctx.Build(pctx, BuildParams{
+		Rule:   WriteFile,
+		Output: path,
+		Args: map[string]string{
+			"content": content,
+		},
+	})
+	return path
+}
+
+func noticeHtml(ctx SingletonContext, partition string, texts []*noticeText, escaped map[string]Paths) Paths {
+	buf := &bytes.Buffer{}
+	fmt.Fprintln(buf, "<html><head>")
+	fmt.Fprintln(buf, "<style type=\"text/css\">")
+	fmt.Fprintln(buf, "body { padding: 0; font-family: sans-serif; }")
+	fmt.Fprintln(buf, ".same-license { background-color: #eeeeee; border-top: 20px solid white; padding: 10px; }")
+	fmt.Fprintln(buf, ".label { font-weight: bold; }")
+	fmt.Fprintln(buf, ".file-list { margin-left: 1em; color: blue; }")
+	fmt.Fprintln(buf, "</style>")
+	fmt.Fprintln(buf, "</head>")
+	fmt.Fprintln(buf, "<body topmargin=\"0\" leftmargin=\"0\" rightmargin=\"0\" bottommargin=\"0\">")
+
+	fmt.Fprintln(buf, "<div class=\"toc\">")
+	fmt.Fprintln(buf, "<ul>")
+	for _, text := range texts {
+		for _, file := range text.files {
+			fmt.Fprintf(buf, "<li><a href=\"#id%s\">%s</a></li>\n", text.id, html.EscapeString(file))
+		}
+	}
+	fmt.Fprintln(buf, "</ul>")
+	fmt.Fprintln(buf, "</div><!-- table of contents -->")
+	fmt.Fprintln(buf, "<table cellpadding=\"0\" cellspacing=\"0\" border=\"0\">")
+	parts := Paths{noticeFragment(ctx, partition, "html_header", buf)}
+
+	buf = &bytes.Buffer{}
+	fmt.Fprintln(buf, "</pre><!-- license-text -->")
+	fmt.Fprintln(buf, "</td></tr><!-- same-license -->")
+	textFooter := noticeFragment(ctx, partition, "html_text_footer", buf)
+
+	for _, text := range texts {
+		buf = &bytes.Buffer{}
+		fmt.Fprintf(buf, "<tr id=\"id%s\"><td class=\"same-license\">\n", text.id)
+		fmt.Fprintln(buf, "<div class=\"label\">Notices for file(s):</div>")
+		fmt.Fprintln(buf, "<div class=\"file-list\">")
+		for _, file := range text.files {
+			fmt.Fprintf(buf, "%s <br/>\n", html.EscapeString(file))
+		}
+		fmt.Fprintln(buf, "</div><!-- file-list -->")
+		fmt.Fprintln(buf, "<pre class=\"license-text\">")
+		parts = append(parts, noticeFragment(ctx, partition, "html_"+text.id, buf),
+			escaped[text.id][0], textFooter)
+	}
+
+	buf = &bytes.Buffer{}
+	fmt.Fprintln(buf, "</table>")
+	fmt.Fprintln(buf, "</body></html>")
+	parts = append(parts, noticeFragment(ctx, partition, "html_footer", buf))
+
+	return parts
+}
+
+func noticeXml(ctx SingletonContext, partition string, texts []*noticeText, escaped map[string]Paths) Paths {
+	buf := &bytes.Buffer{}
+	fmt.Fprintln(buf, "<?xml version=\"1.0\" encoding=\"utf-8\"?>")
+	fmt.Fprintln(buf, "<licenses>")
+	for _, text := range texts {
+		for _, file := range text.files {
+			fmt.Fprintf(buf, "<file-name contentId=\"%s\">%s</file-name>\n", text.id, html.EscapeString(file))
+		}
+	}
+	parts := Paths{noticeFragment(ctx, partition, "xml_header", buf)}
+
+	buf = &bytes.Buffer{}
+	fmt.Fprintln(buf, "]]></file-content>")
+	textFooter := noticeFragment(ctx, partition, "xml_text_footer", buf)
+
+	for _, text := range texts {
+		buf = &bytes.Buffer{}
+		fmt.Fprintf(buf, "<file-content contentId=\"%s\"><![CDATA[", text.id)
+		parts = append(parts, noticeFragment(ctx, partition, "xml_"+text.id, buf),
+			escaped[text.id][1], textFooter)
+	}
+
+	buf = &bytes.Buffer{}
+	fmt.Fprintln(buf, "</licenses>")
+	parts = append(parts, noticeFragment(ctx, partition, "xml_footer", buf))
+
+	return parts
+}
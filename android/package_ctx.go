@@ -10,10 +10,12 @@ import (
 
 type PackageContext struct {
 	blueprint.PackageContext
+
+	pkgPath string
 }
 
 func NewPackageContext(pkgPath string) PackageContext {
-	return PackageContext{blueprint.NewPackageContext(pkgPath)}
+	return PackageContext{blueprint.NewPackageContext(pkgPath), pkgPath}
 }
 
 type configErrorWrapper struct {
@@ -812,6 +812,8 @@ func PathForModuleInstall(ctx ModuleInstallPathContext, pathComponents ...string
 			partition = ctx.DeviceConfig().OdmPath()
 		} else if ctx.ProductSpecific() {
 			partition = ctx.DeviceConfig().ProductPath()
+		} else if ctx.SystemExtSpecific() {
+			partition = ctx.DeviceConfig().SystemExtPath()
 		} else {
 			partition = "system"
 		}
@@ -1,11 +1,17 @@
 package android
 
 import (
+	"encoding/json"
 	"fmt"
+	"sort"
 
 	"github.com/google/blueprint"
 )
 
+func init() {
+	RegisterSingletonType("prebuilt_selection", PrebuiltSelectionSingleton)
+}
+
 type prebuiltDependencyTag struct {
 	blueprint.BaseDependencyTag
 }
@@ -13,9 +19,16 @@ type prebuiltDependencyTag struct {
 var prebuiltDepTag prebuiltDependencyTag
 
 type PrebuiltProperties struct {
-	Prefer       *bool `android:"arch_variant"`
-	SourceExists bool  `blueprint:"mutated"`
-	UsePrebuilt  bool  `blueprint:"mutated"`
+	Prefer *bool `android:"arch_variant"`
+
+	Prefer_config_var struct {
+		Config_namespace *string
+		Var_name         *string
+	}
+
+	SourceExists    bool   `blueprint:"mutated"`
+	UsePrebuilt     bool   `blueprint:"mutated"`
+	SelectionReason string `blueprint:"mutated"`
 }
 
 type Prebuilt struct {
@@ -82,12 +95,14 @@ func PrebuiltSelectModuleMutator(ctx TopDownMutatorContext) {
 			panic(fmt.Errorf("prebuilt module did not have InitPrebuiltModule called on it"))
 		}
 		if !p.properties.SourceExists {
-			p.properties.UsePrebuilt = p.usePrebuilt(ctx, nil)
+			p.properties.UsePrebuilt, p.properties.SelectionReason = p.usePrebuilt(ctx, m, nil)
 		}
 	} else if s, ok := ctx.Module().(Module); ok {
 		ctx.VisitDirectDepsWithTag(prebuiltDepTag, func(m Module) {
 			p := m.(PrebuiltInterface).Prebuilt()
-			if p.usePrebuilt(ctx, s) {
+			usePrebuilt, reason := p.usePrebuilt(ctx, m, s)
+			p.properties.SelectionReason = reason
+			if usePrebuilt {
 				p.properties.UsePrebuilt = true
 				s.SkipInstall()
 			}
@@ -112,14 +127,98 @@ func PrebuiltPostDepsMutator(ctx BottomUpMutatorContext) {
 	}
 }
 
-func (p *Prebuilt) usePrebuilt(ctx TopDownMutatorContext, source Module) bool {
+func (p *Prebuilt) usePrebuilt(ctx TopDownMutatorContext, prebuilt, source Module) (bool, string) {
 	if len(*p.srcs) == 0 {
-		return false
+		return false, "prebuilt has no srcs"
+	}
+
+	if namespace, name := p.properties.Prefer_config_var.Config_namespace,
+		p.properties.Prefer_config_var.Var_name; namespace != nil || name != nil {
+		if namespace == nil || name == nil {
+			// usePrebuilt is also called from the source module, report the error on the prebuilt.
+			ctx.OtherModuleErrorf(prebuilt, "prefer_config_var: config_namespace and var_name must both be set")
+			return false, "invalid prefer_config_var"
+		}
+		config := ctx.Config().VendorConfig(*namespace)
+		if config.IsSet(*name) {
+			prefer := config.Bool(*name)
+			return prefer || source == nil || !source.Enabled(),
+				fmt.Sprintf("vendor variable %s.%s is %q", *namespace, *name, config.String(*name))
+		}
+	}
+
+	if ctx.Config().PreferPrebuiltForPath(ctx.OtherModuleDir(prebuilt)) {
+		return true, "module directory is listed in PreferPrebuiltPaths"
 	}
 
 	if Bool(p.properties.Prefer) {
-		return true
+		return true, "prefer property is true"
+	}
+
+	if source == nil {
+		return true, "no source module"
+	}
+	if !source.Enabled() {
+		return true, "source module is disabled"
 	}
 
-	return source == nil || !source.Enabled()
+	return false, "source module is preferred by default"
+}
+
+func PrebuiltSelectionSingleton() Singleton {
+	return &prebuiltSelectionSingleton{}
+}
+
+type prebuiltSelectionSingleton struct{}
+
+type prebuiltSelection struct {
+	Name          string `json:"name"`
+	Variant       string `json:"variant"`
+	Prebuilt_dir  string `json:"prebuilt_dir"`
+	Source_exists bool   `json:"source_exists"`
+	Chosen        string `json:"chosen"`
+	Reason        string `json:"reason"`
+}
+
+func (s *prebuiltSelectionSingleton) GenerateBuildActions(ctx SingletonContext) {
+	selections := []prebuiltSelection{}
+
+	ctx.VisitAllModules(func(module Module) {
+		m, ok := module.(PrebuiltInterface)
+		if !ok || m.Prebuilt() == nil {
+			return
+		}
+		p := m.Prebuilt()
+
+		chosen := "source"
+		if p.properties.UsePrebuilt {
+			chosen = "prebuilt"
+		}
+
+		selections = append(selections, prebuiltSelection{
+			Name:          m.base().BaseModuleName(),
+			Variant:       ctx.ModuleSubDir(module),
+			Prebuilt_dir:  ctx.ModuleDir(module),
+			Source_exists: p.properties.SourceExists,
+			Chosen:        chosen,
+			Reason:        p.properties.SelectionReason,
+		})
+	})
+
+	sort.SliceStable(selections, func(i, j int) bool {
+		if selections[i].Name != selections[j].Name {
+			return selections[i].Name < selections[j].Name
+		}
+		return selections[i].Variant < selections[j].Variant
+	})
+
+	data, err := json.MarshalIndent(selections, "", "  ")
+	if err != nil {
+		ctx.Errorf("failed to marshal prebuilt selections: %s", err.Error())
+		return
+	}
+
+	if err := writeFileIfChanged(PathForOutput(ctx, "prebuilt_selection.json").String(), append(data, '\n')); err != nil {
+		ctx.Errorf("%s", err.Error())
+	}
 }
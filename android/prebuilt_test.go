@@ -0,0 +1,291 @@
+package android
+
+import (
+	"testing"
+
+	"github.com/google/blueprint"
+)
+
+var prebuiltsTests = []struct {
+	name       string
+	fs         map[string][]byte
+	preferDirs []string
+	vendorVars map[string]map[string]string
+	prebuilt   bool
+	reason     string
+	err        string
+}{
+	{
+		name: "no prebuilt",
+		fs: map[string][]byte{
+			"Android.bp": []byte(`
+				source {
+					name: "bar",
+				}`),
+		},
+	},
+	{
+		name: "no source",
+		fs: map[string][]byte{
+			"prebuilts/bar/Android.bp": []byte(`
+				prebuilt {
+					name: "bar",
+					srcs: ["prebuilt_file"],
+				}`),
+		},
+		prebuilt: true,
+		reason:   "no source module",
+	},
+	{
+		name: "source preferred by default",
+		fs: map[string][]byte{
+			"Android.bp": []byte(`
+				source {
+					name: "bar",
+				}`),
+			"prebuilts/bar/Android.bp": []byte(`
+				prebuilt {
+					name: "bar",
+					srcs: ["prebuilt_file"],
+				}`),
+		},
+		prebuilt: false,
+		reason:   "source module is preferred by default",
+	},
+	{
+		name: "prefer property",
+		fs: map[string][]byte{
+			"Android.bp": []byte(`
+				source {
+					name: "bar",
+				}`),
+			"prebuilts/bar/Android.bp": []byte(`
+				prebuilt {
+					name: "bar",
+					prefer: true,
+					srcs: ["prebuilt_file"],
+				}`),
+		},
+		prebuilt: true,
+		reason:   "prefer property is true",
+	},
+	{
+		name: "prefer path",
+		fs: map[string][]byte{
+			"Android.bp": []byte(`
+				source {
+					name: "bar",
+				}`),
+			"prebuilts/bar/Android.bp": []byte(`
+				prebuilt {
+					name: "bar",
+					srcs: ["prebuilt_file"],
+				}`),
+		},
+		preferDirs: []string{"prebuilts"},
+		prebuilt:   true,
+		reason:     "module directory is listed in PreferPrebuiltPaths",
+	},
+	{
+		name: "prefer path is not a prefix of sibling directories",
+		fs: map[string][]byte{
+			"Android.bp": []byte(`
+				source {
+					name: "bar",
+				}`),
+			"prebuilts/barbaz/Android.bp": []byte(`
+				prebuilt {
+					name: "bar",
+					srcs: ["prebuilt_file"],
+				}`),
+		},
+		preferDirs: []string{"prebuilts/bar"},
+		prebuilt:   false,
+		reason:     "source module is preferred by default",
+	},
+	{
+		name: "prefer config var overrides prefer property",
+		fs: map[string][]byte{
+			"Android.bp": []byte(`
+				source {
+					name: "bar",
+				}`),
+			"prebuilts/bar/Android.bp": []byte(`
+				prebuilt {
+					name: "bar",
+					prefer: true,
+					prefer_config_var: {
+						config_namespace: "acme",
+						var_name: "use_prebuilts",
+					},
+					srcs: ["prebuilt_file"],
+				}`),
+		},
+		vendorVars: map[string]map[string]string{
+			"acme": {"use_prebuilts": "false"},
+		},
+		prebuilt: false,
+		reason:   `vendor variable acme.use_prebuilts is "false"`,
+	},
+	{
+		name: "unset prefer config var falls back to prefer property",
+		fs: map[string][]byte{
+			"Android.bp": []byte(`
+				source {
+					name: "bar",
+				}`),
+			"prebuilts/bar/Android.bp": []byte(`
+				prebuilt {
+					name: "bar",
+					prefer: true,
+					prefer_config_var: {
+						config_namespace: "acme",
+						var_name: "use_prebuilts",
+					},
+					srcs: ["prebuilt_file"],
+				}`),
+		},
+		prebuilt: true,
+		reason:   "prefer property is true",
+	},
+	{
+		name: "incomplete prefer config var is reported on the prebuilt",
+		fs: map[string][]byte{
+			"Android.bp": []byte(`
+				source {
+					name: "bar",
+				}`),
+			"prebuilts/bar/Android.bp": []byte(`
+				prebuilt {
+					name: "bar",
+					prefer_config_var: {
+						config_namespace: "acme",
+					},
+					srcs: ["prebuilt_file"],
+				}`),
+		},
+		err: `module "prebuilt_bar".*prefer_config_var: config_namespace and var_name must both be set`,
+	},
+}
+
+func TestPrebuilts(t *testing.T) {
+	for _, test := range prebuiltsTests {
+		t.Run(test.name, func(t *testing.T) {
+			config := TestConfig(buildDir, nil)
+			config.TestProductVariables.PreferPrebuiltPaths = test.preferDirs
+			config.TestProductVariables.VendorVars = test.vendorVars
+
+			ctx := NewTestContext()
+			ctx.RegisterModuleType("prebuilt", ModuleFactoryAdaptor(newPrebuiltModule))
+			ctx.RegisterModuleType("source", ModuleFactoryAdaptor(newSourceModule))
+			ctx.PreArchMutators(RegisterPrebuiltsPreArchMutators)
+			ctx.PostDepsMutators(RegisterPrebuiltsPostDepsMutators)
+			ctx.Register()
+
+			var files []string
+			for file := range test.fs {
+				files = append(files, file)
+			}
+			ctx.MockFileSystem(test.fs)
+
+			_, errs := ctx.ParseFileList(".", files)
+			FailIfErrored(t, errs)
+			_, errs = ctx.ResolveDependencies(config)
+			if test.err != "" {
+				FailIfNoMatchingErrors(t, test.err, errs)
+				return
+			}
+			FailIfErrored(t, errs)
+
+			found := false
+			ctx.VisitAllModules(func(m blueprint.Module) {
+				p, ok := m.(*prebuiltModule)
+				if !ok {
+					return
+				}
+				found = true
+				if p.prebuilt.properties.UsePrebuilt != test.prebuilt {
+					t.Errorf("expected prebuilt to be used: %t, got %t",
+						test.prebuilt, p.prebuilt.properties.UsePrebuilt)
+				}
+				if p.prebuilt.properties.SelectionReason != test.reason {
+					t.Errorf("expected selection reason %q, got %q",
+						test.reason, p.prebuilt.properties.SelectionReason)
+				}
+			})
+			if found != (test.reason != "") {
+				t.Errorf("expected prebuilt module to exist: %t, got %t", test.reason != "", found)
+			}
+		})
+	}
+}
+
+func TestPreferPrebuiltForPath(t *testing.T) {
+	config := TestConfig(buildDir, nil)
+	config.TestProductVariables.PreferPrebuiltPaths = []string{"prebuilts/foo", "vendor/"}
+
+	testCases := []struct {
+		path     string
+		expected bool
+	}{
+		{"prebuilts/foo", true},
+		{"prebuilts/foo/bar", true},
+		{"prebuilts/foobar", false},
+		{"prebuilts", false},
+		{"vendor", true},
+		{"vendor/acme", true},
+		{"vendorx", false},
+	}
+
+	for _, testCase := range testCases {
+		if got := config.PreferPrebuiltForPath(testCase.path); got != testCase.expected {
+			t.Errorf("PreferPrebuiltForPath(%q): expected %t, got %t", testCase.path, testCase.expected, got)
+		}
+	}
+}
+
+type prebuiltModule struct {
+	ModuleBase
+	prebuilt   Prebuilt
+	properties struct {
+		Srcs []string
+	}
+}
+
+func newPrebuiltModule() Module {
+	m := &prebuiltModule{}
+	m.AddProperties(&m.properties)
+	InitPrebuiltModule(m, &m.properties.Srcs)
+	InitAndroidModule(m)
+	return m
+}
+
+func (p *prebuiltModule) Name() string {
+	return p.prebuilt.Name(p.ModuleBase.Name())
+}
+
+func (p *prebuiltModule) DepsMutator(ctx BottomUpMutatorContext) {
+}
+
+func (p *prebuiltModule) GenerateAndroidBuildActions(ctx ModuleContext) {
+}
+
+func (p *prebuiltModule) Prebuilt() *Prebuilt {
+	return &p.prebuilt
+}
+
+type sourceModule struct {
+	ModuleBase
+}
+
+func newSourceModule() Module {
+	m := &sourceModule{}
+	InitAndroidModule(m)
+	return m
+}
+
+func (s *sourceModule) DepsMutator(ctx BottomUpMutatorContext) {
+}
+
+func (s *sourceModule) GenerateAndroidBuildActions(ctx ModuleContext) {
+}
@@ -40,9 +40,13 @@ func ModuleFactoryAdaptor(factory ModuleFactory) blueprint.ModuleFactory {
 type SingletonFactory func() Singleton
 
 func SingletonFactoryAdaptor(factory SingletonFactory) blueprint.SingletonFactory {
+	return namedSingletonFactoryAdaptor("", factory)
+}
+
+func namedSingletonFactoryAdaptor(name string, factory SingletonFactory) blueprint.SingletonFactory {
 	return func() blueprint.Singleton {
 		singleton := factory()
-		return singletonAdaptor{singleton}
+		return singletonAdaptor{Singleton: singleton, name: name}
 	}
 }
 
@@ -51,11 +55,11 @@ func RegisterModuleType(name string, factory ModuleFactory) {
 }
 
 func RegisterSingletonType(name string, factory SingletonFactory) {
-	singletons = append(singletons, singleton{name, SingletonFactoryAdaptor(factory)})
+	singletons = append(singletons, singleton{name, namedSingletonFactoryAdaptor(name, factory)})
 }
 
 func RegisterPreSingletonType(name string, factory SingletonFactory) {
-	preSingletons = append(preSingletons, singleton{name, SingletonFactoryAdaptor(factory)})
+	preSingletons = append(preSingletons, singleton{name, namedSingletonFactoryAdaptor(name, factory)})
 }
 
 type Context struct {
@@ -81,5 +85,9 @@ func (ctx *Context) Register() {
 
 	registerMutators(ctx.Context, preArch, preDeps, postDeps)
 
-	ctx.RegisterSingletonType("env", SingletonFactoryAdaptor(EnvSingleton))
+	ctx.RegisterSingletonType("host_tools", namedSingletonFactoryAdaptor("host_tools", HostToolsSingleton))
+	ctx.RegisterSingletonType("build_graph_lint", namedSingletonFactoryAdaptor("build_graph_lint", BuildGraphLintSingleton))
+	// soong_metrics runs after the other singletons so that their timings are included.
+	ctx.RegisterSingletonType("soong_metrics", namedSingletonFactoryAdaptor("soong_metrics", MetricsSingleton))
+	ctx.RegisterSingletonType("env", namedSingletonFactoryAdaptor("env", EnvSingleton))
 }
@@ -1,6 +1,8 @@
 package android
 
 import (
+	"time"
+
 	"github.com/google/blueprint"
 	"github.com/google/blueprint/pathtools"
 )
@@ -22,6 +24,7 @@ type SingletonContext interface {
 	SetNinjaBuildDir(pctx PackageContext, value string)
 	Eval(pctx PackageContext, ninjaStr string) (string, error)
 	VisitAllModules(visit func(Module))
+	VisitAllModulesBlueprint(visit func(blueprint.Module))
 	VisitAllModulesIf(pred func(Module) bool, visit func(Module))
 	VisitDepsDepthFirst(module Module, visit func(Module))
 	VisitDepsDepthFirstIf(module Module, pred func(Module) bool, visit func(Module))
@@ -35,10 +38,16 @@ type SingletonContext interface {
 
 type singletonAdaptor struct {
 	Singleton
+
+	name string
 }
 
 func (s singletonAdaptor) GenerateBuildActions(ctx blueprint.SingletonContext) {
-	s.Singleton.GenerateBuildActions(singletonContextAdaptor{ctx})
+	begin := time.Now()
+	s.Singleton.GenerateBuildActions(singletonContextAdaptor{SingletonContext: ctx, name: s.name})
+	if s.name != "" {
+		ctx.Config().(Config).recordPhase("singleton:"+s.name, begin, time.Now())
+	}
 }
 
 type Singleton interface {
@@ -47,10 +56,16 @@ type Singleton interface {
 
 type singletonContextAdaptor struct {
 	blueprint.SingletonContext
+
+	name string
 }
 
 func (s singletonContextAdaptor) Config() Config {
-	return s.SingletonContext.Config().(Config)
+	config := s.SingletonContext.Config().(Config)
+	if s.name != "" {
+		return config.withEnvReader(s.name + " (singleton)")
+	}
+	return config
 }
 
 func (s singletonContextAdaptor) Variable(pctx PackageContext, name, value string) {
@@ -62,6 +77,9 @@ func (s singletonContextAdaptor) Rule(pctx PackageContext, name string, params b
 }
 
 func (s singletonContextAdaptor) Build(pctx PackageContext, params BuildParams) {
+	if s.Config().captureBuild {
+		s.Config().captureSingletonBuild(params)
+	}
 	bparams := convertBuildParams(params)
 	s.SingletonContext.Build(pctx.PackageContext, bparams)
 
@@ -97,6 +115,10 @@ func (s singletonContextAdaptor) VisitAllModules(visit func(Module)) {
 	s.SingletonContext.VisitAllModules(visitAdaptor(visit))
 }
 
+func (s singletonContextAdaptor) VisitAllModulesBlueprint(visit func(blueprint.Module)) {
+	s.SingletonContext.VisitAllModules(visit)
+}
+
 func (s singletonContextAdaptor) VisitAllModulesIf(pred func(Module) bool, visit func(Module)) {
 	s.SingletonContext.VisitAllModulesIf(predAdaptor(pred), visitAdaptor(visit))
 }
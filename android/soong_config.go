@@ -0,0 +1,444 @@
+package android
+
+import (
+	"bufio"
+	"bytes"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+
+	"github.com/google/blueprint"
+	"github.com/google/blueprint/parser"
+	"github.com/google/blueprint/proptools"
+)
+
+const soongConfigConditionsDefault = "conditions_default"
+
+func init() {
+	RegisterModuleType("soong_config_module_type", SoongConfigModuleTypeFactory)
+	RegisterModuleType("soong_config_string_variable", SoongConfigStringVariableFactory)
+	RegisterModuleType("soong_config_bool_variable", SoongConfigBoolVariableFactory)
+}
+
+type soongConfigModuleTypeProperties struct {
+	Module_type      *string
+	Config_namespace *string
+	Variables        []string
+	Bool_variables   []string
+	Properties       []string
+}
+
+type soongConfigVariableProperties struct {
+	Config_namespace *string
+}
+
+type soongConfigStringVariableProperties struct {
+	Values []string
+}
+
+type soongConfigDeclarationModule struct {
+	ModuleBase
+}
+
+func (m *soongConfigDeclarationModule) DepsMutator(ctx BottomUpMutatorContext) {}
+
+func (m *soongConfigDeclarationModule) GenerateAndroidBuildActions(ctx ModuleContext) {}
+
+func newSoongConfigDeclarationModule(props ...interface{}) Module {
+	module := &soongConfigDeclarationModule{}
+	module.AddProperties(props...)
+	InitAndroidModule(module)
+	return module
+}
+
+func SoongConfigModuleTypeFactory() Module {
+	return newSoongConfigDeclarationModule(&soongConfigModuleTypeProperties{})
+}
+
+func SoongConfigStringVariableFactory() Module {
+	return newSoongConfigDeclarationModule(&soongConfigVariableProperties{},
+		&soongConfigStringVariableProperties{})
+}
+
+func SoongConfigBoolVariableFactory() Module {
+	return newSoongConfigDeclarationModule(&soongConfigVariableProperties{})
+}
+
+type soongConfigModuleType struct {
+	name          string
+	pos           string
+	moduleType    string
+	namespace     string
+	variables     []string
+	boolVariables []string
+	properties    []string
+
+	stringValues map[string][]string
+	propsType    reflect.Type
+}
+
+// soongConfigVariable identifies a variable, variables are scoped to their config_namespace.
+type soongConfigVariable struct {
+	namespace string
+	name      string
+}
+
+type soongConfigDeclarations struct {
+	moduleTypes     []*soongConfigModuleType
+	stringVariables map[soongConfigVariable][]string
+	boolVariables   map[soongConfigVariable]bool
+	positions       map[soongConfigVariable]string
+}
+
+func newSoongConfigDeclarations() *soongConfigDeclarations {
+	return &soongConfigDeclarations{
+		stringVariables: make(map[soongConfigVariable][]string),
+		boolVariables:   make(map[soongConfigVariable]bool),
+		positions:       make(map[soongConfigVariable]string),
+	}
+}
+
+func (ctx *Context) RegisterSoongConfigModuleTypes(config Config, moduleListFile string) []error {
+	if moduleListFile == "" {
+		return nil
+	}
+
+	list, err := ioutil.ReadFile(moduleListFile)
+	if err != nil {
+		return []error{err}
+	}
+
+	decls := newSoongConfigDeclarations()
+
+	var errs []error
+	scanner := bufio.NewScanner(bytes.NewReader(list))
+	for scanner.Scan() {
+		file := strings.TrimSpace(scanner.Text())
+		if file == "" {
+			continue
+		}
+		errs = append(errs, decls.scanFile(filepath.Join(config.srcDir, file))...)
+	}
+	if len(errs) > 0 {
+		return errs
+	}
+
+	factories := make(map[string]blueprint.ModuleFactory)
+	for _, t := range moduleTypes {
+		factories[t.name] = t.factory
+	}
+
+	for _, t := range decls.moduleTypes {
+		if _, exists := factories[t.name]; exists {
+			errs = append(errs, fmt.Errorf("%s: module type %q is already defined", t.pos, t.name))
+			continue
+		}
+
+		base, ok := factories[t.moduleType]
+		if !ok {
+			errs = append(errs, fmt.Errorf("%s: module_type %q of %q does not exist", t.pos, t.moduleType, t.name))
+			continue
+		}
+
+		if err := decls.resolve(t, base); err != nil {
+			errs = append(errs, err)
+			continue
+		}
+
+		factories[t.name] = t.factory(base)
+		ctx.RegisterModuleType(t.name, factories[t.name])
+	}
+
+	return errs
+}
+
+func (d *soongConfigDeclarations) scanFile(filename string) []error {
+	data, err := ioutil.ReadFile(filename)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil
+		}
+		return []error{err}
+	}
+
+	if !bytes.Contains(data, []byte("soong_config_")) {
+		return nil
+	}
+
+	file, errs := parser.ParseAndEval(filename, bytes.NewReader(data), parser.NewScope(nil))
+	if len(errs) > 0 {
+		// Blueprint reports syntax errors when it parses the same file.
+		return nil
+	}
+
+	for _, def := range file.Defs {
+		module, ok := def.(*parser.Module)
+		if !ok {
+			continue
+		}
+
+		pos := module.TypePos.String()
+		name := bpStringProperty(module, "name")
+
+		variable := soongConfigVariable{bpStringProperty(module, "config_namespace"), name}
+
+		switch module.Type {
+		case "soong_config_string_variable", "soong_config_bool_variable":
+			if !isSoongConfigIdentifier(name) {
+				errs = append(errs, fmt.Errorf("%s: invalid soong config variable name %q", pos, name))
+				continue
+			}
+			if variable.namespace == "" {
+				errs = append(errs, fmt.Errorf("%s: %s %q must set config_namespace", pos, module.Type, name))
+				continue
+			}
+			if prev, exists := d.positions[variable]; exists {
+				errs = append(errs, fmt.Errorf("%s: soong config variable %s.%s is already declared at %s",
+					pos, variable.namespace, name, prev))
+				continue
+			}
+			d.positions[variable] = pos
+		}
+
+		switch module.Type {
+		case "soong_config_module_type":
+			d.moduleTypes = append(d.moduleTypes, &soongConfigModuleType{
+				name:          name,
+				pos:           pos,
+				moduleType:    bpStringProperty(module, "module_type"),
+				namespace:     bpStringProperty(module, "config_namespace"),
+				variables:     bpListProperty(module, "variables"),
+				boolVariables: bpListProperty(module, "bool_variables"),
+				properties:    bpListProperty(module, "properties"),
+			})
+		case "soong_config_string_variable":
+			values := bpListProperty(module, "values")
+			if len(values) == 0 {
+				errs = append(errs, fmt.Errorf("%s: soong_config_string_variable %q must have values", pos, name))
+			}
+			for _, value := range values {
+				if !isSoongConfigIdentifier(value) || value == soongConfigConditionsDefault {
+					errs = append(errs, fmt.Errorf("%s: invalid value %q for soong_config_string_variable %q", pos, value, name))
+				}
+			}
+			if dup, ok := firstDuplicate(values); ok {
+				errs = append(errs, fmt.Errorf("%s: soong_config_string_variable %q lists value %q more than once",
+					pos, name, dup))
+			}
+			d.stringVariables[variable] = values
+		case "soong_config_bool_variable":
+			d.boolVariables[variable] = true
+		}
+	}
+
+	return errs
+}
+
+func bpStringProperty(module *parser.Module, name string) string {
+	for _, prop := range module.Properties {
+		if prop.Name == name {
+			if s, ok := prop.Value.Eval().(*parser.String); ok {
+				return s.Value
+			}
+		}
+	}
+	return ""
+}
+
+func bpListProperty(module *parser.Module, name string) []string {
+	var ret []string
+	for _, prop := range module.Properties {
+		if prop.Name == name {
+			if list, ok := prop.Value.Eval().(*parser.List); ok {
+				for _, v := range list.Values {
+					if s, ok := v.Eval().(*parser.String); ok {
+						ret = append(ret, s.Value)
+					}
+				}
+			}
+		}
+	}
+	return ret
+}
+
+func isSoongConfigIdentifier(s string) bool {
+	if s == "" {
+		return false
+	}
+	for i, c := range s {
+		if !(c >= 'a' && c <= 'z' || c == '_' || i > 0 && c >= '0' && c <= '9') {
+			return false
+		}
+	}
+	return true
+}
+
+// firstDuplicate returns the first entry of list that maps to the same struct field name as an
+// earlier entry.
+func firstDuplicate(list []string) (string, bool) {
+	seen := make(map[string]bool)
+	for _, s := range list {
+		name := proptools.FieldNameForProperty(s)
+		if seen[name] {
+			return s, true
+		}
+		seen[name] = true
+	}
+	return "", false
+}
+
+func (d *soongConfigDeclarations) resolve(t *soongConfigModuleType, base blueprint.ModuleFactory) error {
+	if t.namespace == "" {
+		return fmt.Errorf("%s: soong_config_module_type %q must set config_namespace", t.pos, t.name)
+	}
+	if len(t.properties) == 0 {
+		return fmt.Errorf("%s: soong_config_module_type %q must set properties", t.pos, t.name)
+	}
+
+	// reflect.StructOf panics on duplicate field names
+	for _, list := range []struct {
+		property string
+		entries  []string
+	}{
+		{"properties", t.properties},
+		{"variables", t.variables},
+		{"bool_variables", t.boolVariables},
+	} {
+		if dup, ok := firstDuplicate(list.entries); ok {
+			return fmt.Errorf("%s: soong_config_module_type %q lists %q more than once in %s",
+				t.pos, t.name, dup, list.property)
+		}
+	}
+	for _, v := range t.boolVariables {
+		if InList(v, t.variables) {
+			return fmt.Errorf("%s: soong_config_module_type %q lists %q in both variables and bool_variables",
+				t.pos, t.name, v)
+		}
+	}
+
+	t.stringValues = make(map[string][]string)
+	for _, v := range t.variables {
+		values, ok := d.stringVariables[soongConfigVariable{t.namespace, v}]
+		if !ok {
+			return fmt.Errorf("%s: soong_config_module_type %q uses undeclared string variable %s.%s",
+				t.pos, t.name, t.namespace, v)
+		}
+		t.stringValues[v] = values
+	}
+	for _, v := range t.boolVariables {
+		if !d.boolVariables[soongConfigVariable{t.namespace, v}] {
+			return fmt.Errorf("%s: soong_config_module_type %q uses undeclared bool variable %s.%s",
+				t.pos, t.name, t.namespace, v)
+		}
+	}
+
+	_, baseProps := base()
+	var fields []reflect.StructField
+	for _, property := range t.properties {
+		field, ok := findPropertyField(baseProps, property)
+		if !ok {
+			return fmt.Errorf("%s: module type %q has no property %q", t.pos, t.moduleType, property)
+		}
+		fields = append(fields, field)
+	}
+	propsType := reflect.StructOf(fields)
+
+	var variableFields []reflect.StructField
+	for _, v := range t.variables {
+		var valueFields []reflect.StructField
+		for _, value := range append(t.stringValues[v], soongConfigConditionsDefault) {
+			valueFields = append(valueFields, reflect.StructField{
+				Name: proptools.FieldNameForProperty(value),
+				Type: propsType,
+			})
+		}
+		variableFields = append(variableFields, reflect.StructField{
+			Name: proptools.FieldNameForProperty(v),
+			Type: reflect.StructOf(valueFields),
+		})
+	}
+	for _, v := range t.boolVariables {
+		boolFields := append([]reflect.StructField(nil), fields...)
+		boolFields = append(boolFields, reflect.StructField{
+			Name: proptools.FieldNameForProperty(soongConfigConditionsDefault),
+			Type: propsType,
+		})
+		variableFields = append(variableFields, reflect.StructField{
+			Name: proptools.FieldNameForProperty(v),
+			Type: reflect.StructOf(boolFields),
+		})
+	}
+
+	t.propsType = reflect.StructOf([]reflect.StructField{{
+		Name: "Soong_config_variables",
+		Type: reflect.StructOf(variableFields),
+	}})
+
+	return nil
+}
+
+func findPropertyField(props []interface{}, property string) (reflect.StructField, bool) {
+	if strings.Contains(property, ".") {
+		return reflect.StructField{}, false
+	}
+	name := proptools.FieldNameForProperty(property)
+	for _, p := range props {
+		t := reflect.TypeOf(p)
+		if t.Kind() != reflect.Ptr || t.Elem().Kind() != reflect.Struct {
+			continue
+		}
+		if field, ok := t.Elem().FieldByName(name); ok {
+			return reflect.StructField{Name: name, Type: field.Type, Tag: field.Tag}, true
+		}
+	}
+	return reflect.StructField{}, false
+}
+
+func (t *soongConfigModuleType) factory(base blueprint.ModuleFactory) blueprint.ModuleFactory {
+	return func() (blueprint.Module, []interface{}) {
+		module, props := base()
+		conditional := reflect.New(t.propsType)
+		props = append(props, conditional.Interface())
+		AddLoadHook(module, func(ctx LoadHookContext) {
+			t.apply(ctx, conditional.Elem())
+		})
+		return module, props
+	}
+}
+
+func (t *soongConfigModuleType) apply(ctx LoadHookContext, conditional reflect.Value) {
+	config := ctx.Config().VendorConfig(t.namespace)
+	variables := conditional.FieldByName("Soong_config_variables")
+
+	for _, v := range t.variables {
+		selected := soongConfigConditionsDefault
+		if config.IsSet(v) {
+			value := config.String(v)
+			if !InList(value, t.stringValues[v]) {
+				ctx.PropertyErrorf("soong_config_variables."+v,
+					"soong config variable %s.%s has value %q, expected one of %q",
+					t.namespace, v, value, t.stringValues[v])
+				continue
+			}
+			selected = value
+		}
+		props := variables.FieldByName(proptools.FieldNameForProperty(v)).
+			FieldByName(proptools.FieldNameForProperty(selected))
+		ctx.AppendProperties(props.Addr().Interface())
+	}
+
+	for _, v := range t.boolVariables {
+		field := variables.FieldByName(proptools.FieldNameForProperty(v))
+		props := field.FieldByName(proptools.FieldNameForProperty(soongConfigConditionsDefault))
+		if config.Bool(v) {
+			props = reflect.New(props.Type()).Elem()
+			for i := 0; i < props.NumField(); i++ {
+				props.Field(i).Set(field.Field(i))
+			}
+		}
+		ctx.AppendProperties(props.Addr().Interface())
+	}
+}
@@ -0,0 +1,325 @@
+package android
+
+import (
+	"io/ioutil"
+	"path/filepath"
+	"reflect"
+	"testing"
+)
+
+type soongConfigTestModule struct {
+	ModuleBase
+	properties struct {
+		Cflags []string
+		Srcs   []string
+	}
+}
+
+func newSoongConfigTestModule() Module {
+	m := &soongConfigTestModule{}
+	m.AddProperties(&m.properties)
+	InitAndroidModule(m)
+	return m
+}
+
+func (m *soongConfigTestModule) DepsMutator(ctx BottomUpMutatorContext) {
+}
+
+func (m *soongConfigTestModule) GenerateAndroidBuildActions(ctx ModuleContext) {
+}
+
+func testSoongConfigDeclarations(t *testing.T, bp string) (*soongConfigDeclarations, []error) {
+	t.Helper()
+
+	dir, err := ioutil.TempDir(buildDir, "soong_config")
+	if err != nil {
+		t.Fatal(err)
+	}
+	file := filepath.Join(dir, "Android.bp")
+	if err := ioutil.WriteFile(file, []byte(bp), 0666); err != nil {
+		t.Fatal(err)
+	}
+
+	decls := newSoongConfigDeclarations()
+	errs := decls.scanFile(file)
+	if len(errs) > 0 {
+		return decls, errs
+	}
+
+	base := ModuleFactoryAdaptor(newSoongConfigTestModule)
+	for _, moduleType := range decls.moduleTypes {
+		if err := decls.resolve(moduleType, base); err != nil {
+			errs = append(errs, err)
+		}
+	}
+	return decls, errs
+}
+
+func TestSoongConfigDeclarationErrors(t *testing.T) {
+	testCases := []struct {
+		name string
+		bp   string
+		err  string
+	}{
+		{
+			name: "duplicate property",
+			bp: `
+				soong_config_module_type {
+					name: "acme_test_module",
+					module_type: "test_module",
+					config_namespace: "acme",
+					properties: ["cflags", "cflags"],
+				}`,
+			err: `lists "cflags" more than once in properties`,
+		},
+		{
+			name: "duplicate variable",
+			bp: `
+				soong_config_string_variable {
+					name: "board",
+					config_namespace: "acme",
+					values: ["soc_a"],
+				}
+
+				soong_config_module_type {
+					name: "acme_test_module",
+					module_type: "test_module",
+					config_namespace: "acme",
+					variables: ["board", "board"],
+					properties: ["cflags"],
+				}`,
+			err: `lists "board" more than once in variables`,
+		},
+		{
+			name: "duplicate bool variable",
+			bp: `
+				soong_config_bool_variable {
+					name: "feature",
+					config_namespace: "acme",
+				}
+
+				soong_config_module_type {
+					name: "acme_test_module",
+					module_type: "test_module",
+					config_namespace: "acme",
+					bool_variables: ["feature", "feature"],
+					properties: ["cflags"],
+				}`,
+			err: `lists "feature" more than once in bool_variables`,
+		},
+		{
+			name: "variable and bool variable",
+			bp: `
+				soong_config_string_variable {
+					name: "board",
+					config_namespace: "acme",
+					values: ["soc_a"],
+				}
+
+				soong_config_module_type {
+					name: "acme_test_module",
+					module_type: "test_module",
+					config_namespace: "acme",
+					variables: ["board"],
+					bool_variables: ["board"],
+					properties: ["cflags"],
+				}`,
+			err: `lists "board" in both variables and bool_variables`,
+		},
+		{
+			name: "duplicate value",
+			bp: `
+				soong_config_string_variable {
+					name: "board",
+					config_namespace: "acme",
+					values: ["soc_a", "soc_a"],
+				}`,
+			err: `lists value "soc_a" more than once`,
+		},
+		{
+			name: "variable declared twice in a namespace",
+			bp: `
+				soong_config_bool_variable {
+					name: "feature",
+					config_namespace: "acme",
+				}
+
+				soong_config_string_variable {
+					name: "feature",
+					config_namespace: "acme",
+					values: ["a"],
+				}`,
+			err: `soong config variable acme.feature is already declared at`,
+		},
+		{
+			name: "variable without namespace",
+			bp: `
+				soong_config_bool_variable {
+					name: "feature",
+				}`,
+			err: `soong_config_bool_variable "feature" must set config_namespace`,
+		},
+		{
+			name: "variable of another namespace",
+			bp: `
+				soong_config_bool_variable {
+					name: "feature",
+					config_namespace: "other",
+				}
+
+				soong_config_module_type {
+					name: "acme_test_module",
+					module_type: "test_module",
+					config_namespace: "acme",
+					bool_variables: ["feature"],
+					properties: ["cflags"],
+				}`,
+			err: `uses undeclared bool variable acme.feature`,
+		},
+		{
+			name: "unknown property",
+			bp: `
+				soong_config_module_type {
+					name: "acme_test_module",
+					module_type: "test_module",
+					config_namespace: "acme",
+					properties: ["unknown"],
+				}`,
+			err: `module type "test_module" has no property "unknown"`,
+		},
+	}
+
+	for _, testCase := range testCases {
+		t.Run(testCase.name, func(t *testing.T) {
+			_, errs := testSoongConfigDeclarations(t, testCase.bp)
+			FailIfNoMatchingErrors(t, testCase.err, errs)
+		})
+	}
+}
+
+func TestSoongConfigModuleType(t *testing.T) {
+	bp := `
+		soong_config_string_variable {
+			name: "board",
+			config_namespace: "acme",
+			values: ["soc_a", "soc_b"],
+		}
+
+		soong_config_bool_variable {
+			name: "feature",
+			config_namespace: "acme",
+		}
+
+		soong_config_bool_variable {
+			name: "feature",
+			config_namespace: "other",
+		}
+
+		soong_config_module_type {
+			name: "acme_test_module",
+			module_type: "test_module",
+			config_namespace: "acme",
+			variables: ["board"],
+			bool_variables: ["feature"],
+			properties: ["cflags", "srcs"],
+		}
+
+		acme_test_module {
+			name: "foo",
+			cflags: ["-DGENERIC"],
+			soong_config_variables: {
+				board: {
+					soc_a: {
+						cflags: ["-DSOC_A"],
+					},
+					soc_b: {
+						cflags: ["-DSOC_B"],
+					},
+					conditions_default: {
+						cflags: ["-DSOC_DEFAULT"],
+					},
+				},
+				feature: {
+					cflags: ["-DFEATURE"],
+					srcs: ["feature.c"],
+					conditions_default: {
+						cflags: ["-DNO_FEATURE"],
+					},
+				},
+			},
+		}
+	`
+
+	testCases := []struct {
+		name       string
+		vendorVars map[string]map[string]string
+		cflags     []string
+		srcs       []string
+	}{
+		{
+			name:   "unset",
+			cflags: []string{"-DGENERIC", "-DSOC_DEFAULT", "-DNO_FEATURE"},
+		},
+		{
+			name: "set",
+			vendorVars: map[string]map[string]string{
+				"acme": {
+					"board":   "soc_b",
+					"feature": "true",
+				},
+			},
+			cflags: []string{"-DGENERIC", "-DSOC_B", "-DFEATURE"},
+			srcs:   []string{"feature.c"},
+		},
+		{
+			name: "other namespace",
+			vendorVars: map[string]map[string]string{
+				"other": {
+					"board":   "soc_b",
+					"feature": "true",
+				},
+			},
+			cflags: []string{"-DGENERIC", "-DSOC_DEFAULT", "-DNO_FEATURE"},
+		},
+	}
+
+	decls, errs := testSoongConfigDeclarations(t, bp)
+	FailIfErrored(t, errs)
+
+	for _, testCase := range testCases {
+		t.Run(testCase.name, func(t *testing.T) {
+			config := TestConfig(buildDir, nil)
+			config.TestProductVariables.VendorVars = testCase.vendorVars
+
+			ctx := NewTestContext()
+			ctx.RegisterModuleType("test_module", ModuleFactoryAdaptor(newSoongConfigTestModule))
+			ctx.RegisterModuleType("soong_config_module_type", ModuleFactoryAdaptor(SoongConfigModuleTypeFactory))
+			ctx.RegisterModuleType("soong_config_string_variable", ModuleFactoryAdaptor(SoongConfigStringVariableFactory))
+			ctx.RegisterModuleType("soong_config_bool_variable", ModuleFactoryAdaptor(SoongConfigBoolVariableFactory))
+			base := ModuleFactoryAdaptor(newSoongConfigTestModule)
+			for _, moduleType := range decls.moduleTypes {
+				ctx.RegisterModuleType(moduleType.name, moduleType.factory(base))
+			}
+			ctx.PreArchMutators(func(ctx RegisterMutatorsContext) {
+				ctx.TopDown("load_hooks", loadHookMutator).Parallel()
+			})
+			ctx.Register()
+
+			ctx.MockFileSystem(map[string][]byte{
+				"Android.bp": []byte(bp),
+			})
+			_, errs := ctx.ParseFileList(".", []string{"Android.bp"})
+			FailIfErrored(t, errs)
+			_, errs = ctx.ResolveDependencies(config)
+			FailIfErrored(t, errs)
+
+			foo := ctx.ModuleForTests("foo", "").Module().(*soongConfigTestModule)
+			if !reflect.DeepEqual(foo.properties.Cflags, testCase.cflags) {
+				t.Errorf("expected cflags %q, got %q", testCase.cflags, foo.properties.Cflags)
+			}
+			if !reflect.DeepEqual(foo.properties.Srcs, testCase.srcs) {
+				t.Errorf("expected srcs %q, got %q", testCase.srcs, foo.properties.Srcs)
+			}
+		})
+	}
+}
@@ -1,6 +1,8 @@
 package android
 
 import (
+	"bytes"
+	"io/ioutil"
 	"path/filepath"
 	"runtime"
 	"sort"
@@ -176,16 +178,15 @@ func GetNumericSdkVersion(v string) string {
 	return v
 }
 
-func Prefix() string {
-	return "/data/data/com.termux/files/usr"
-}
-
-func TermuxExecutable(exec string) string {
-	return Prefix() + "/bin/" + exec
-}
-
 func StringToPath(pathComponents ...string) Path {
 	path := filepath.Join(pathComponents...)
 	ret := basePath{path: path, rel: "/"}
 	return ret
 }
+
+func writeFileIfChanged(filename string, data []byte) error {
+	if existing, err := ioutil.ReadFile(filename); err == nil && bytes.Equal(existing, data) {
+		return nil
+	}
+	return ioutil.WriteFile(filename, data, 0666)
+}
@@ -104,9 +104,21 @@ type productVariables struct {
 	DeviceSecondaryCpuVariant  *string   `json:",omitempty"`
 	DeviceSecondaryAbi         *[]string `json:",omitempty"`
 
+	DeviceNativeBridgeArch        *string   `json:",omitempty"`
+	DeviceNativeBridgeArchVariant *string   `json:",omitempty"`
+	DeviceNativeBridgeCpuVariant  *string   `json:",omitempty"`
+	DeviceNativeBridgeAbi         *[]string `json:",omitempty"`
+
+	DeviceNativeBridgeSecondaryArch        *string   `json:",omitempty"`
+	DeviceNativeBridgeSecondaryArchVariant *string   `json:",omitempty"`
+	DeviceNativeBridgeSecondaryCpuVariant  *string   `json:",omitempty"`
+	DeviceNativeBridgeSecondaryAbi         *[]string `json:",omitempty"`
+
 	HostArch          *string `json:",omitempty"`
 	HostSecondaryArch *string `json:",omitempty"`
 
+	HostArm64GlibcGccRoot *string `json:",omitempty"`
+
 	CrossHost              *string `json:",omitempty"`
 	CrossHostArch          *string `json:",omitempty"`
 	CrossHostSecondaryArch *string `json:",omitempty"`
@@ -151,6 +163,8 @@ type productVariables struct {
 	OdmPath     *string `json:",omitempty"`
 	ProductPath *string `json:",omitempty"`
 
+	SystemExtPath *string `json:",omitempty"`
+
 	UseClangLld *bool `json:",omitempty"`
 
 	ClangTidy  *bool   `json:",omitempty"`
@@ -183,7 +197,14 @@ type productVariables struct {
 
 	PgoAdditionalProfileDirs []string `json:",omitempty"`
 
+	PreferPrebuiltPaths []string `json:",omitempty"`
+
 	VendorVars map[string]map[string]string `json:",omitempty"`
+
+	HostToolsPrefix    *string `json:",omitempty"`
+	HostToolsUsePath   *bool   `json:",omitempty"`
+	HostToolsMap       *string `json:",omitempty"`
+	HostToolsSkipCheck *bool   `json:",omitempty"`
 }
 
 func boolPtr(v bool) *bool {
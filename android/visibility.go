@@ -0,0 +1,214 @@
+package android
+
+import (
+	"fmt"
+	"path/filepath"
+	"strings"
+)
+
+const (
+	visibilityPublic      = "//visibility:public"
+	visibilityPrivate     = "//visibility:private"
+	visibilityNamespace   = "//visibility:namespace"
+	visibilityPackage     = "__pkg__"
+	visibilitySubpackages = "__subpackages__"
+)
+
+func registerVisibilityMutator(ctx RegisterMutatorsContext) {
+	ctx.TopDown("visibility", visibilityMutator)
+}
+
+type visibilityRule struct {
+	rule        string
+	dir         string
+	subpackages bool
+	name        string
+	relative    bool
+}
+
+func parseVisibilityRule(rule string) (visibilityRule, error) {
+	switch rule {
+	case visibilityPublic, visibilityPrivate, visibilityNamespace:
+		return visibilityRule{rule: rule}, nil
+	}
+
+	if !strings.HasPrefix(rule, "//") {
+		name := strings.TrimPrefix(rule, ":")
+		if name == "" || strings.ContainsAny(name, "/:") {
+			return visibilityRule{}, fmt.Errorf("invalid visibility rule %q, must start with // or be a module name", rule)
+		}
+		return visibilityRule{rule: rule, name: name, relative: true}, nil
+	}
+
+	colon := strings.LastIndex(rule, ":")
+	if colon == -1 {
+		return visibilityRule{}, fmt.Errorf("invalid visibility rule %q, must be of the form //path:%s, //path:%s or //namespace:name",
+			rule, visibilityPackage, visibilitySubpackages)
+	}
+
+	dir := filepath.Clean(strings.TrimPrefix(rule[:colon], "//"))
+	if dir == "visibility" {
+		return visibilityRule{}, fmt.Errorf("unknown visibility rule %q, expected one of %q, %q or %q",
+			rule, visibilityPublic, visibilityPrivate, visibilityNamespace)
+	}
+
+	switch name := rule[colon+1:]; name {
+	case visibilityPackage:
+		return visibilityRule{rule: rule, dir: dir}, nil
+	case visibilitySubpackages:
+		return visibilityRule{rule: rule, dir: dir, subpackages: true}, nil
+	case "":
+		return visibilityRule{}, fmt.Errorf("invalid visibility rule %q, name must be %s, %s or a module name",
+			rule, visibilityPackage, visibilitySubpackages)
+	default:
+		return visibilityRule{rule: rule, dir: dir, name: name}, nil
+	}
+}
+
+type visibilityDepender struct {
+	dir       string
+	name      string
+	namespace string
+}
+
+func namespacePath(namespace *Namespace) string {
+	if namespace == nil {
+		return "."
+	}
+	return namespace.Path
+}
+
+func resolveNamespace(namespace *Namespace, name string) (string, bool) {
+	if namespace == nil {
+		return ".", true
+	}
+	for _, candidate := range namespace.visibleNamespaces {
+		if _, found := candidate.moduleContainer.ModuleFromName(name, nil); found {
+			return candidate.Path, true
+		}
+	}
+	return "", false
+}
+
+func (r visibilityRule) matches(depender visibilityDepender, owner *ModuleBase) bool {
+	switch r.rule {
+	case visibilityPublic:
+		return true
+	case visibilityPrivate:
+		return false
+	case visibilityNamespace:
+		return depender.namespace == namespacePath(owner.namespace)
+	}
+
+	if r.relative {
+		if depender.name != r.name {
+			return false
+		}
+		namespace, found := resolveNamespace(owner.namespace, r.name)
+		return found && depender.namespace == namespace
+	}
+
+	if r.name != "" {
+		if depender.name != r.name {
+			return false
+		}
+		if depender.namespace == "." {
+			return depender.dir == r.dir
+		}
+		return depender.namespace == r.dir
+	}
+
+	if depender.dir == r.dir {
+		return true
+	}
+	return r.subpackages && (r.dir == "." || strings.HasPrefix(depender.dir, r.dir+"/"))
+}
+
+func validateVisibility(ctx TopDownMutatorContext, rules []string) {
+	for _, v := range rules {
+		if _, err := parseVisibilityRule(v); err != nil {
+			ctx.PropertyErrorf("visibility", "%s", err.Error())
+		}
+	}
+}
+
+func (a *ModuleBase) visibleTo(moduleDir string, depender visibilityDepender) bool {
+	rules := a.commonProperties.Visibility
+	if len(rules) == 0 || moduleDir == depender.dir {
+		return true
+	}
+
+	for _, v := range rules {
+		r, err := parseVisibilityRule(v)
+		if err != nil {
+			continue
+		}
+		if r.matches(depender, a) {
+			return true
+		}
+	}
+	return false
+}
+
+type visibilityCheckKey struct {
+	dir  string
+	name string
+}
+
+type visibilityErrorKey struct {
+	dir     string
+	name    string
+	depDir  string
+	depName string
+}
+
+// onceForModule returns true the first time it is called with key, so that problems shared by
+// all variants of a module are only reported once.
+func onceForModule(ctx TopDownMutatorContext, key interface{}) bool {
+	first := false
+	ctx.Config().Once(key, func() interface{} {
+		first = true
+		return true
+	})
+	return first
+}
+
+func visibilityMutator(ctx TopDownMutatorContext) {
+	m := ctx.Module()
+	if m == nil {
+		return
+	}
+
+	if onceForModule(ctx, visibilityCheckKey{ctx.ModuleDir(), ctx.ModuleName()}) {
+		validateVisibility(ctx, m.base().commonProperties.Visibility)
+	}
+
+	depender := visibilityDepender{
+		dir:       ctx.ModuleDir(),
+		name:      ctx.ModuleName(),
+		namespace: namespacePath(m.base().namespace),
+	}
+
+	// Variants can have different dependencies, so the dependencies of every variant are
+	// checked.
+	ctx.VisitDirectDeps(func(dep Module) {
+		if ctx.OtherModuleDependencyTag(dep) == DefaultsDepTag {
+			return
+		}
+
+		depDir := ctx.OtherModuleDir(dep)
+		if dep.base().visibleTo(depDir, depender) {
+			return
+		}
+
+		depName := ctx.OtherModuleName(dep)
+		if !onceForModule(ctx, visibilityErrorKey{depender.dir, depender.name, depDir, depName}) {
+			return
+		}
+
+		ctx.ModuleErrorf("depends on //%s:%s which is not visible to this module\n"+
+			"You may need to add %q to its visibility, which is currently %q",
+			depDir, depName, "//"+depender.dir+":"+visibilityPackage,
+			dep.base().commonProperties.Visibility)
+	})
+}
@@ -0,0 +1,359 @@
+package android
+
+import (
+	"regexp"
+	"testing"
+
+	"github.com/google/blueprint"
+)
+
+var visibilityTests = []struct {
+	name           string
+	fs             map[string][]byte
+	expectedErrors []string
+}{
+	{
+		name: "__pkg__ allows the listed package only",
+		fs: map[string][]byte{
+			"top/Android.bp": []byte(`
+				mock_library {
+					name: "libexample",
+					visibility: ["//top/nested:__pkg__"],
+				}
+
+				mock_library {
+					name: "libsamepackage",
+					deps: ["libexample"],
+				}`),
+			"top/nested/Android.bp": []byte(`
+				mock_library {
+					name: "libnested",
+					deps: ["libexample"],
+				}`),
+			"top/nested/again/Android.bp": []byte(`
+				mock_library {
+					name: "libnestedagain",
+					deps: ["libexample"],
+				}`),
+			"other/Android.bp": []byte(`
+				mock_library {
+					name: "libother",
+					deps: ["libexample"],
+				}`),
+		},
+		expectedErrors: []string{
+			`"libnestedagain".*depends on //top:libexample which is not visible to this module`,
+			`"libother".*depends on //top:libexample which is not visible to this module`,
+		},
+	},
+	{
+		name: "__subpackages__ allows the package and everything below it",
+		fs: map[string][]byte{
+			"top/Android.bp": []byte(`
+				mock_library {
+					name: "libexample",
+					visibility: ["//top/nested:__subpackages__"],
+				}`),
+			"top/nested/Android.bp": []byte(`
+				mock_library {
+					name: "libnested",
+					deps: ["libexample"],
+				}`),
+			"top/nested/again/Android.bp": []byte(`
+				mock_library {
+					name: "libnestedagain",
+					deps: ["libexample"],
+				}`),
+			"top/nestedagain/Android.bp": []byte(`
+				mock_library {
+					name: "libsimilarname",
+					deps: ["libexample"],
+				}`),
+		},
+		expectedErrors: []string{
+			`"libsimilarname".*depends on //top:libexample which is not visible to this module`,
+		},
+	},
+	{
+		name: "//visibility:private only allows the same package",
+		fs: map[string][]byte{
+			"top/Android.bp": []byte(`
+				mock_library {
+					name: "libexample",
+					visibility: ["//visibility:private"],
+				}
+
+				mock_library {
+					name: "libsamepackage",
+					deps: ["libexample"],
+				}`),
+			"top/nested/Android.bp": []byte(`
+				mock_library {
+					name: "libnested",
+					deps: ["libexample"],
+				}`),
+		},
+		expectedErrors: []string{
+			`"libnested".*depends on //top:libexample which is not visible to this module`,
+		},
+	},
+	{
+		name: "//visibility:public allows everything",
+		fs: map[string][]byte{
+			"top/Android.bp": []byte(`
+				mock_library {
+					name: "libexample",
+					visibility: ["//visibility:public"],
+				}`),
+			"other/Android.bp": []byte(`
+				mock_library {
+					name: "libother",
+					deps: ["libexample"],
+				}`),
+		},
+	},
+	{
+		name: "module rule allows only the named module",
+		fs: map[string][]byte{
+			"top/Android.bp": []byte(`
+				mock_library {
+					name: "libexample",
+					visibility: ["//other:libother"],
+				}`),
+			"other/Android.bp": []byte(`
+				mock_library {
+					name: "libother",
+					deps: ["libexample"],
+				}
+
+				mock_library {
+					name: "libotherother",
+					deps: ["libexample"],
+				}`),
+		},
+		expectedErrors: []string{
+			`"libotherother".*depends on //top:libexample which is not visible to this module`,
+		},
+	},
+	{
+		name: "//visibility:namespace allows the namespace only",
+		fs: map[string][]byte{
+			"ns1/Android.bp": []byte(`
+				soong_namespace {
+				}
+
+				mock_library {
+					name: "libns1",
+					visibility: ["//visibility:namespace"],
+				}`),
+			"ns1/sub/Android.bp": []byte(`
+				mock_library {
+					name: "libns1sub",
+					deps: ["libns1"],
+				}`),
+			"ns2/Android.bp": []byte(`
+				soong_namespace {
+					imports: ["ns1"],
+				}
+
+				mock_library {
+					name: "libns2",
+					deps: ["libns1"],
+				}`),
+		},
+		expectedErrors: []string{
+			`"libns2".*depends on //ns1:libns1 which is not visible to this module`,
+		},
+	},
+	{
+		name: "namespace module rules",
+		fs: map[string][]byte{
+			"ns1/Android.bp": []byte(`
+				soong_namespace {
+				}
+
+				mock_library {
+					name: "libns1",
+					visibility: [
+						"//ns2:libns2",
+						":libroot",
+					],
+				}`),
+			"ns1/sub/Android.bp": []byte(`
+				mock_library {
+					name: "libns1sub",
+					deps: ["libns1"],
+				}`),
+			"ns2/Android.bp": []byte(`
+				soong_namespace {
+					imports: ["ns1"],
+				}
+
+				mock_library {
+					name: "libns2",
+					deps: ["libns1"],
+				}
+
+				mock_library {
+					name: "libns2other",
+					deps: ["libns1"],
+				}`),
+			"Android.bp": []byte(`
+				mock_library {
+					name: "libroot",
+					deps: ["//ns1:libns1"],
+				}`),
+		},
+		expectedErrors: []string{
+			`"libns1sub".*depends on //ns1:libns1 which is not visible to this module`,
+			`"libns2other".*depends on //ns1:libns1 which is not visible to this module`,
+		},
+	},
+	{
+		name: "dependencies of every variant are checked",
+		fs: map[string][]byte{
+			"top/Android.bp": []byte(`
+				mock_library {
+					name: "libexample",
+					visibility: ["//visibility:private"],
+				}`),
+			"other/Android.bp": []byte(`
+				mock_library {
+					name: "libother",
+					target: {
+						host: {
+							deps: ["libexample"],
+						},
+					},
+				}`),
+		},
+		expectedErrors: []string{
+			`"libother".*depends on //top:libexample which is not visible to this module`,
+		},
+	},
+	{
+		name: "invalid rules",
+		fs: map[string][]byte{
+			"top/Android.bp": []byte(`
+				mock_library {
+					name: "libexample",
+					visibility: [
+						"//visibility:unknown",
+						"top/nested",
+						"//top/nested",
+					],
+				}`),
+		},
+		expectedErrors: []string{
+			`unknown visibility rule "//visibility:unknown"`,
+			`invalid visibility rule "top/nested"`,
+			`invalid visibility rule "//top/nested"`,
+		},
+	},
+}
+
+func TestVisibility(t *testing.T) {
+	for _, test := range visibilityTests {
+		t.Run(test.name, func(t *testing.T) {
+			errs := testVisibility(buildDir, test.fs)
+
+			if len(test.expectedErrors) == 0 {
+				FailIfErrored(t, errs)
+				return
+			}
+
+			for _, expected := range test.expectedErrors {
+				FailIfNoMatchingErrors(t, expected, errs)
+			}
+			if len(errs) != len(test.expectedErrors) {
+				t.Errorf("expected %d errors, found %d", len(test.expectedErrors), len(errs))
+				for i, err := range errs {
+					t.Errorf("errs[%d] = %s", i, err)
+				}
+			}
+		})
+	}
+}
+
+func TestVisibilityErrorReportedOnce(t *testing.T) {
+	errs := testVisibility(buildDir, map[string][]byte{
+		"top/Android.bp": []byte(`
+			mock_library {
+				name: "libexample",
+				visibility: ["//visibility:private", "//visibility:unknown"],
+			}`),
+		"other/Android.bp": []byte(`
+			mock_library {
+				name: "libother",
+				deps: ["libexample"],
+			}`),
+	})
+
+	for _, pattern := range []string{
+		`depends on //top:libexample which is not visible`,
+		`unknown visibility rule`,
+	} {
+		matcher := regexp.MustCompile(pattern)
+		count := 0
+		for _, err := range errs {
+			if matcher.MatchString(err.Error()) {
+				count++
+			}
+		}
+		if count != 1 {
+			t.Errorf("expected %q to be reported once for all variants, found %d times", pattern, count)
+		}
+	}
+}
+
+func testVisibility(buildDir string, fs map[string][]byte) []error {
+	config := TestArchConfig(buildDir, nil)
+
+	ctx := NewTestArchContext()
+	ctx.RegisterModuleType("soong_namespace", ModuleFactoryAdaptor(NamespaceFactory))
+	ctx.RegisterModuleType("mock_library", ModuleFactoryAdaptor(newMockLibraryModule))
+	ctx.PreArchMutators(RegisterNamespaceMutator)
+	ctx.PostDepsMutators(registerVisibilityMutator)
+	ctx.Register()
+
+	var files []string
+	for file := range fs {
+		files = append(files, file)
+	}
+	ctx.MockFileSystem(fs)
+
+	_, errs := ctx.ParseFileList(".", files)
+	if len(errs) > 0 {
+		return errs
+	}
+
+	_, errs = ctx.ResolveDependencies(config)
+	return errs
+}
+
+type mockLibraryProperties struct {
+	Deps []string `android:"arch_variant"`
+}
+
+type mockLibraryModule struct {
+	ModuleBase
+	properties mockLibraryProperties
+}
+
+func newMockLibraryModule() Module {
+	m := &mockLibraryModule{}
+	m.AddProperties(&m.properties)
+	InitAndroidArchModule(m, HostAndDeviceSupported, MultilibCommon)
+	return m
+}
+
+type mockDependencyTag struct {
+	blueprint.BaseDependencyTag
+}
+
+func (m *mockLibraryModule) DepsMutator(ctx BottomUpMutatorContext) {
+	ctx.AddDependency(ctx.Module(), mockDependencyTag{}, m.properties.Deps...)
+}
+
+func (m *mockLibraryModule) GenerateAndroidBuildActions(ctx ModuleContext) {
+}
@@ -4,9 +4,12 @@ import (
 	"fmt"
 	"os"
 	"path/filepath"
+	"reflect"
+	"sort"
 	"strings"
 
 	"github.com/google/blueprint"
+	"github.com/google/blueprint/proptools"
 )
 
 func init() {
@@ -31,6 +34,8 @@ func primaryBuilderPath(ctx SingletonContext) Path {
 
 func (c *docsSingleton) GenerateBuildActions(ctx SingletonContext) {
 	docsFile := PathForOutput(ctx, "docs", "soong_build.html")
+	jsonDocsFile := PathForOutput(ctx, "docs", "soong_build.json")
+	markdownDocsFile := PathForOutput(ctx, "docs", "soong_build.md")
 	primaryBuilder := primaryBuilderPath(ctx)
 	soongDocs := ctx.Rule(pctx, "soongDocs", blueprint.RuleParams{
 		Command:     fmt.Sprintf("%s --soong_docs %s %s", primaryBuilder.String(), docsFile.String(), strings.Join(os.Args[1:], " ")),
@@ -39,13 +44,168 @@ func (c *docsSingleton) GenerateBuildActions(ctx SingletonContext) {
 	})
 
 	ctx.Build(pctx, BuildParams{
-		Rule:   soongDocs,
-		Output: docsFile,
+		Rule:            soongDocs,
+		Output:          docsFile,
+		ImplicitOutputs: WritablePaths{jsonDocsFile, markdownDocsFile},
 	})
 
 	ctx.Build(pctx, BuildParams{
 		Rule:   blueprint.Phony,
 		Output: PathForPhony(ctx, "soong_docs"),
-		Input:  docsFile,
+		Inputs: Paths{docsFile, jsonDocsFile, markdownDocsFile},
 	})
 }
+
+type ModuleTypeDoc struct {
+	Name       string         `json:"name"`
+	Text       string         `json:"text,omitempty"`
+	Properties []*PropertyDoc `json:"properties"`
+}
+
+type PropertyDoc struct {
+	Name        string         `json:"name"`
+	Type        string         `json:"type"`
+	ArchVariant bool           `json:"arch_variant,omitempty"`
+	Mutated     bool           `json:"mutated,omitempty"`
+	Text        string         `json:"text,omitempty"`
+	Defaults    []string       `json:"defaults,omitempty"`
+	Properties  []*PropertyDoc `json:"properties,omitempty"`
+}
+
+func (p *PropertyDoc) Find(name string) *PropertyDoc {
+	for _, child := range p.Properties {
+		if child.Name == name {
+			return child
+		}
+	}
+	return nil
+}
+
+func (m *ModuleTypeDoc) Find(name string) *PropertyDoc {
+	return (&PropertyDoc{Properties: m.Properties}).Find(name)
+}
+
+func ModuleTypeDocs() []*ModuleTypeDoc {
+	defaultsTypes := make(map[string][]reflect.Type)
+	for _, t := range moduleTypes {
+		module, _ := t.factory()
+		if defaults, ok := module.(Defaults); ok {
+			for _, props := range defaults.properties() {
+				defaultsTypes[t.name] = append(defaultsTypes[t.name], reflect.TypeOf(props))
+			}
+		}
+	}
+
+	var docs []*ModuleTypeDoc
+	for _, t := range moduleTypes {
+		module, props := t.factory()
+		doc := &ModuleTypeDoc{Name: t.name}
+
+		var archProperties []interface{}
+		var defaultableTypes []reflect.Type
+		if m, ok := module.(Module); ok {
+			archProperties = m.base().archProperties
+			if defaultable, ok := module.(Defaultable); ok {
+				for _, p := range defaultable.defaultablePropertyStructs() {
+					defaultableTypes = append(defaultableTypes, reflect.TypeOf(p))
+				}
+			}
+		}
+
+		for _, p := range props {
+			if inInterfaceList(p, archProperties) {
+				continue
+			}
+
+			var defaults []string
+			if inTypeList(reflect.TypeOf(p), defaultableTypes) {
+				for name, types := range defaultsTypes {
+					if inTypeList(reflect.TypeOf(p), types) {
+						defaults = append(defaults, name)
+					}
+				}
+				sort.Strings(defaults)
+			}
+
+			root := &PropertyDoc{Properties: doc.Properties}
+			propertyDocs(root, reflect.ValueOf(p).Elem(), false, defaults)
+			doc.Properties = root.Properties
+		}
+
+		docs = append(docs, doc)
+	}
+
+	sort.Slice(docs, func(i, j int) bool { return docs[i].Name < docs[j].Name })
+	return docs
+}
+
+func propertyDocs(parent *PropertyDoc, v reflect.Value, mutated bool, defaults []string) {
+	for i := 0; i < v.NumField(); i++ {
+		field := v.Type().Field(i)
+		if field.PkgPath != "" {
+			continue
+		}
+
+		fieldValue := v.Field(i)
+		fieldMutated := mutated || proptools.HasTag(field, "blueprint", "mutated")
+
+		if field.Anonymous && fieldValue.Kind() == reflect.Struct {
+			propertyDocs(parent, fieldValue, fieldMutated, defaults)
+			continue
+		}
+
+		name := proptools.PropertyNameForField(field.Name)
+		doc := parent.Find(name)
+		if doc == nil {
+			doc = &PropertyDoc{Name: name}
+			parent.Properties = append(parent.Properties, doc)
+		}
+		doc.ArchVariant = doc.ArchVariant || proptools.HasTag(field, "android", "arch_variant")
+		doc.Mutated = doc.Mutated || fieldMutated
+		doc.Defaults = FirstUniqueStrings(append(doc.Defaults, defaults...))
+
+		switch {
+		case fieldValue.Kind() == reflect.Interface && !fieldValue.IsNil() &&
+			fieldValue.Elem().Kind() == reflect.Ptr && fieldValue.Elem().Elem().Kind() == reflect.Struct:
+			doc.Type = "struct"
+			propertyDocs(doc, fieldValue.Elem().Elem(), fieldMutated, defaults)
+		case field.Type.Kind() == reflect.Struct:
+			doc.Type = structTypeName(field.Type)
+			propertyDocs(doc, fieldValue, fieldMutated, defaults)
+		case field.Type.Kind() == reflect.Ptr && field.Type.Elem().Kind() == reflect.Struct:
+			doc.Type = "*" + structTypeName(field.Type.Elem())
+			if fieldValue.IsNil() {
+				propertyDocs(doc, reflect.Zero(field.Type.Elem()), fieldMutated, defaults)
+			} else {
+				propertyDocs(doc, fieldValue.Elem(), fieldMutated, defaults)
+			}
+		default:
+			doc.Type = field.Type.String()
+		}
+	}
+}
+
+func structTypeName(t reflect.Type) string {
+	if t.Name() == "" {
+		return "struct"
+	}
+	return t.String()
+}
+
+func inInterfaceList(i interface{}, list []interface{}) bool {
+	for _, l := range list {
+		if l == i {
+			return true
+		}
+	}
+	return false
+}
+
+func inTypeList(t reflect.Type, list []reflect.Type) bool {
+	for _, l := range list {
+		if l == t {
+			return true
+		}
+	}
+	return false
+}
@@ -10,7 +10,8 @@ import (
 )
 
 var (
-	vendorSuffix = ".vendor"
+	vendorSuffix       = ".vendor"
+	nativeBridgeSuffix = ".native_bridge"
 )
 
 type AndroidMkContext interface {
@@ -85,6 +86,10 @@ func (c *Module) AndroidMk() android.AndroidMkData {
 		ret.SubName += vendorSuffix
 	}
 
+	if c.Target().NativeBridge {
+		ret.SubName += nativeBridgeSuffix
+	}
+
 	return ret
 }
 
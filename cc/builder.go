@@ -82,7 +82,7 @@ var (
 		"objcopyCmd", "prefix")
 
 	_ = pctx.StaticVariable("stripPath", "build/soong/scripts/strip.sh")
-	_ = pctx.StaticVariable("xzCmd", android.TermuxExecutable("xz"))
+	_ = pctx.HostToolVariable("xzCmd", "xz")
 
 	strip = pctx.AndroidStaticRule("strip", blueprint.RuleParams{
 		Depfile:     "${out}.d",
@@ -122,7 +122,7 @@ var (
 	},
 		"cFlags", "tidyFlags")
 
-	_ = pctx.StaticVariable("yasmCmd", android.TermuxExecutable("yasm"))
+	_ = pctx.HostToolVariable("yasmCmd", "yasm")
 
 	yasm = pctx.AndroidStaticRule("yasm", blueprint.RuleParams{
 		Command:     "$yasmCmd $asFlags -o $out $in && $yasmCmd $asFlags -M $in >$out.d",
@@ -138,7 +138,7 @@ var (
 	},
 		"windresCmd", "flags")
 
-	_ = pctx.StaticVariable("sAbiDumper", android.TermuxExecutable("header-abi-dumper"))
+	_ = pctx.HostToolVariable("sAbiDumper", "header-abi-dumper")
 
 	sAbiDump = pctx.AndroidStaticRule("sAbiDump", blueprint.RuleParams{
 		Command:     "rm -f $out && $sAbiDumper -o ${out} $in $exportDirs -- $cFlags -w -isystem ${config.RSIncludePath}",
@@ -146,7 +146,7 @@ var (
 	},
 		"cFlags", "exportDirs")
 
-	_ = pctx.StaticVariable("sAbiLinker", android.TermuxExecutable("header-abi-linker"))
+	_ = pctx.HostToolVariable("sAbiLinker", "header-abi-linker")
 
 	sAbiLink = pctx.AndroidStaticRule("sAbiLink", blueprint.RuleParams{
 		Command:        "$sAbiLinker -o ${out} $symbolFilter -arch $arch  $exportedHeaderFlags @${out}.rsp ",
@@ -156,7 +156,7 @@ var (
 	},
 		"symbolFilter", "arch", "exportedHeaderFlags")
 
-	_ = pctx.StaticVariable("sAbiDiffer", android.TermuxExecutable("header-abi-diff"))
+	_ = pctx.HostToolVariable("sAbiDiffer", "header-abi-diff")
 
 	sAbiDiff = pctx.AndroidRuleFunc("sAbiDiff", func(ctx android.PackageRuleContext) blueprint.RuleParams {
 		commandStr := "($sAbiDiffer $allowFlags -lib $libName -arch $arch -check-all-apis -o ${out} -new $in -old $referenceDump)"
@@ -341,7 +341,7 @@ func TransformSourceToObj(ctx android.ModuleContext, subdir string, srcFiles and
 		}
 
 		ccDesc := ccCmd
-		ccCmd = android.TermuxExecutable(ccCmd)
+		ccCmd = ctx.Config().HostTool(ccCmd)
 
 		var implicitOutputs android.WritablePaths
 		if coverage {
@@ -408,7 +408,7 @@ func TransformSourceToObj(ctx android.ModuleContext, subdir string, srcFiles and
 }
 
 func TransformObjToStaticLib(ctx android.ModuleContext, objFiles android.Paths, flags builderFlags, outputFile android.ModuleOutPath, deps android.Paths) {
-	arCmd := android.TermuxExecutable("llvm-ar")
+	arCmd := ctx.Config().HostTool("llvm-ar")
 	arFlags := "crsD -format=gnu"
 	if flags.arGoldPlugin {
 		arFlags += " --plugin ${config.LLVMGoldPlugin}"
@@ -502,9 +502,9 @@ func transformDarwinObjToStaticLib(ctx android.ModuleContext, objFiles android.P
 func TransformObjToDynamicBinary(ctx android.ModuleContext, objFiles, sharedLibs, staticLibs, lateStaticLibs, wholeStaticLibs, deps android.Paths, groupLate bool, flags builderFlags, outputFile android.WritablePath) {
 	var ldCmd string
 	if flags.clang {
-		ldCmd = android.TermuxExecutable("clang++")
+		ldCmd = ctx.Config().HostTool("clang++")
 	} else {
-		ldCmd = android.TermuxExecutable("g++-7")
+		ldCmd = ctx.Config().HostTool("g++-7")
 	}
 
 	var libFlagsList []string
@@ -627,9 +627,9 @@ func TransformSharedObjectToToc(ctx android.ModuleContext, inputFile android.Pat
 func TransformObjsToObj(ctx android.ModuleContext, objFiles android.Paths, flags builderFlags, outputFile android.WritablePath) {
 	var ldCmd string
 	if flags.clang {
-		ldCmd = android.TermuxExecutable("clang++")
+		ldCmd = ctx.Config().HostTool("clang++")
 	} else {
-		ldCmd = android.TermuxExecutable("g++-7")
+		ldCmd = ctx.Config().HostTool("g++-7")
 	}
 
 	ctx.Build(pctx, android.BuildParams{
@@ -645,7 +645,7 @@ func TransformObjsToObj(ctx android.ModuleContext, objFiles android.Paths, flags
 }
 
 func TransformBinaryPrefixSymbols(ctx android.ModuleContext, prefix string, inputFile android.Path, flags builderFlags, outputFile android.WritablePath) {
-	objcopyCmd := android.TermuxExecutable("objcopy")
+	objcopyCmd := ctx.Config().HostTool("objcopy")
 	ctx.Build(pctx, android.BuildParams{
 		Rule:        prefixSymbols,
 		Description: "prefix symbols " + outputFile.Base(),
@@ -705,7 +705,7 @@ func CopyGccLib(ctx android.ModuleContext, libName string, flags builderFlags, o
 		Description: "copy gcc library " + libName,
 		Output:      outputFile,
 		Args: map[string]string{
-			"ccCmd":   android.TermuxExecutable("gcc-7"),
+			"ccCmd":   ctx.Config().HostTool("gcc-7"),
 			"cFlags":  flags.globalFlags,
 			"libName": libName,
 		},
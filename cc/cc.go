@@ -250,13 +250,33 @@ var (
 	runtimeDepTag         = dependencyTag{name: "runtime lib"}
 )
 
+func (d dependencyTag) DependencyKind() string {
+	switch d.name {
+	case "shared", "late shared":
+		return "shared"
+	case "static", "late static":
+		return "static"
+	case "whole static":
+		return "whole_static"
+	default:
+		return strings.Replace(d.name, " ", "_", -1)
+	}
+}
+
+type ExcludeProperties struct {
+	Exclude_cflags      []string
+	Exclude_static_libs []string
+	Exclude_shared_libs []string
+}
+
 type Module struct {
 	android.ModuleBase
 	android.DefaultableModuleBase
 
-	Properties       BaseProperties
-	VendorProperties VendorProperties
-	unused           UnusedProperties
+	Properties        BaseProperties
+	VendorProperties  VendorProperties
+	unused            UnusedProperties
+	excludeProperties ExcludeProperties
 
 	hod      android.HostOrDeviceSupported
 	multilib android.Multilib
@@ -324,6 +344,7 @@ func (c *Module) Init() android.Module {
 		c.AddProperties(feature.props()...)
 	}
 
+	android.InitDefaultsExclusions(c, &c.excludeProperties)
 	android.InitAndroidArchModule(c, c.hod, c.multilib)
 
 	android.InitDefaultableModule(c)
@@ -654,7 +675,7 @@ func (c *Module) GenerateAndroidBuildActions(actx android.ModuleContext) {
 
 func (c *Module) toolchain(ctx BaseModuleContext) config.Toolchain {
 	if c.cachedToolchain == nil {
-		c.cachedToolchain = config.FindToolchain(ctx.Os(), ctx.Arch())
+		c.cachedToolchain = config.FindToolchainForConfig(ctx.Config(), ctx.Os(), ctx.Arch())
 	}
 	return c.cachedToolchain
 }
@@ -812,7 +833,7 @@ func (c *Module) DepsMutator(actx android.BottomUpMutatorContext) {
 					}
 				} else if ctx.useVndk() && inList(entry, llndkLibraries) {
 					nonvariantLibs = append(nonvariantLibs, entry+llndkLibrarySuffix)
-				} else if (ctx.Platform() || ctx.ProductSpecific()) && inList(entry, vendorPublicLibraries) {
+				} else if (ctx.Platform() || ctx.ProductSpecific() || ctx.SystemExtSpecific()) && inList(entry, vendorPublicLibraries) {
 					vendorPublicLib := entry + vendorPublicLibrarySuffix
 					if actx.OtherModuleExists(vendorPublicLib) {
 						nonvariantLibs = append(nonvariantLibs, vendorPublicLib)
@@ -966,8 +987,14 @@ func (c *Module) depsToPaths(ctx android.ModuleContext) PathDeps {
 
 			case genSourceDepTag:
 				if genRule, ok := dep.(genrule.SourceFileGenerator); ok {
-					depPaths.GeneratedSources = append(depPaths.GeneratedSources,
-						genRule.GeneratedSourceFiles()...)
+					for _, src := range genRule.GeneratedSourceFiles() {
+						if src.Ext() == ".srcjar" {
+							ctx.ModuleErrorf("generated_sources module %q produces %q, which cannot be compiled, "+
+								"use generated_headers to depend on its extracted contents", depName, src.Base())
+							continue
+						}
+						depPaths.GeneratedSources = append(depPaths.GeneratedSources, src)
+					}
 				} else {
 					ctx.ModuleErrorf("module %q is not a gensrcs or genrule", depName)
 				}
@@ -1127,7 +1154,7 @@ func (c *Module) depsToPaths(ctx android.ModuleContext) PathDeps {
 			if c.useVndk() && bothVendorAndCoreVariantsExist {
 
 				return libName + vendorSuffix
-			} else if (ctx.Platform() || ctx.ProductSpecific()) && isVendorPublicLib {
+			} else if (ctx.Platform() || ctx.ProductSpecific() || ctx.SystemExtSpecific()) && isVendorPublicLib {
 				return libName + vendorPublicLibrarySuffix
 			} else {
 				return libName
@@ -1191,6 +1218,15 @@ func (c *Module) Srcs() android.Paths {
 	return android.Paths{}
 }
 
+func (c *Module) TestSuites() []string {
+	if test, ok := c.linker.(interface {
+		testSuites() []string
+	}); ok {
+		return test.testSuites()
+	}
+	return nil
+}
+
 func (c *Module) static() bool {
 	if static, ok := c.linker.(interface {
 		static() bool
@@ -1241,6 +1277,7 @@ func DefaultsFactory(props ...interface{}) android.Module {
 		&LTOProperties{},
 		&PgoProperties{},
 		&android.ProtoProperties{},
+		&ExcludeProperties{},
 	)
 
 	android.InitDefaultsModule(module)
@@ -110,11 +110,11 @@ func generateCLionProject(compiledModule CompiledInterface, ctx android.Singleto
 	f.WriteString(fmt.Sprintf("set(ANDROID_ROOT %s)\n\n", getAndroidSrcRootDirectory(ctx)))
 
 	if ccModule.flags.Clang {
-		f.WriteString(fmt.Sprintf("set(CMAKE_C_COMPILER \"%s%s\")\n", android.TermuxExecutable("clang-7")))
-		f.WriteString(fmt.Sprintf("set(CMAKE_CXX_COMPILER \"%s%s\")\n", android.TermuxExecutable("clang++")))
+		f.WriteString(fmt.Sprintf("set(CMAKE_C_COMPILER \"%s\")\n", ctx.Config().HostTool("clang-7")))
+		f.WriteString(fmt.Sprintf("set(CMAKE_CXX_COMPILER \"%s\")\n", ctx.Config().HostTool("clang++")))
 	} else {
-		f.WriteString(fmt.Sprintf("set(CMAKE_C_COMPILER \"%s%s\")\n", android.TermuxExecutable("gcc-7")))
-		f.WriteString(fmt.Sprintf("set(CMAKE_CXX_COMPILER \"%s%s\")\n", android.TermuxExecutable("g++-7")))
+		f.WriteString(fmt.Sprintf("set(CMAKE_C_COMPILER \"%s\")\n", ctx.Config().HostTool("gcc-7")))
+		f.WriteString(fmt.Sprintf("set(CMAKE_CXX_COMPILER \"%s\")\n", ctx.Config().HostTool("g++-7")))
 	}
 
 	f.WriteString("list(APPEND\n")
@@ -121,7 +121,7 @@ func getArguments(src android.Path, ctx android.SingletonContext, ccModule *Modu
 		isCpp = false
 	}
 
-	args = append(args, android.TermuxExecutable("true"))
+	args = append(args, ctx.Config().HostTool("true"))
 	args = append(args, expandAllVars(ctx, ccModule.flags.GlobalFlags)...)
 	args = append(args, expandAllVars(ctx, ccModule.flags.CFlags)...)
 	if isCpp {
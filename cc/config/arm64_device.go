@@ -92,7 +92,7 @@ func init() {
 
 	replaceFirst(arm64ClangCpuVariantCflags["kryo"], "-mcpu=cortex-a57", "-mcpu=kryo")
 	pctx.StaticVariable("arm64GccVersion", arm64GccVersion)
-	pctx.StaticVariable("Arm64GccRoot", android.Prefix())
+	pctx.HostToolsPrefixVariable("Arm64GccRoot", "")
 	pctx.StaticVariable("Arm64Cflags", strings.Join(arm64Cflags, " "))
 	pctx.StaticVariable("Arm64Ldflags", strings.Join(arm64Ldflags, " "))
 	pctx.StaticVariable("Arm64Lldflags", strings.Join(arm64Lldflags, " "))
@@ -0,0 +1,181 @@
+package config
+
+import (
+	"strings"
+
+	"android/soong/android"
+)
+
+var (
+	linuxArm64Cflags = []string{
+		"-march=armv8-a",
+	}
+
+	linuxArm64ClangCflags = append(ClangFilterUnknownCflags(linuxCflags), []string{
+		"--gcc-toolchain=${LinuxArm64GccRoot}",
+		"--sysroot ${LinuxArm64GccRoot}/sysroot",
+		"-fstack-protector-strong",
+	}...)
+
+	linuxArm64ClangLdflags = append(ClangFilterUnknownCflags(linuxLdflags), []string{
+		"--gcc-toolchain=${LinuxArm64GccRoot}",
+		"--sysroot ${LinuxArm64GccRoot}/sysroot",
+		"-B${LinuxArm64GccRoot}/lib/gcc/${LinuxArm64GccTriple}/${LinuxGccVersion}",
+		"-L${LinuxArm64GccRoot}/lib/gcc/${LinuxArm64GccTriple}/${LinuxGccVersion}",
+		"-L${LinuxArm64GccRoot}/${LinuxArm64GccTriple}/lib64",
+	}...)
+
+	linuxArm64ClangLldflags = ClangFilterUnknownLldflags(linuxArm64ClangLdflags)
+
+	linuxArm64ClangCppflags = []string{
+		"-isystem ${LinuxArm64GccRoot}/${LinuxArm64GccTriple}/include/c++/${LinuxGccVersion}",
+		"-isystem ${LinuxArm64GccRoot}/${LinuxArm64GccTriple}/include/c++/${LinuxGccVersion}/backward",
+		"-isystem ${LinuxArm64GccRoot}/${LinuxArm64GccTriple}/include/c++/${LinuxGccVersion}/${LinuxArm64GccTriple}",
+	}
+
+	linuxBionicArm64Cflags = append(ClangFilterUnknownCflags(linuxBionicCommonCflags),
+		"--gcc-toolchain=${LinuxBionicArm64GccRoot}")
+
+	linuxBionicArm64Ldflags = append(ClangFilterUnknownCflags(linuxBionicCommonLdflags),
+		"--gcc-toolchain=${LinuxBionicArm64GccRoot}")
+
+	linuxBionicArm64Lldflags = ClangFilterUnknownLldflags(linuxBionicArm64Ldflags)
+)
+
+func init() {
+	// There is no prebuilt aarch64 glibc gcc toolchain, products opt into the glibc host
+	// toolchain by setting HostArm64GlibcGccRoot to a locally installed one.
+	pctx.VariableFunc("LinuxArm64GccRoot", func(ctx android.PackageVarContext) string {
+		return ctx.Config().HostArm64GlibcGccRoot()
+	})
+
+	pctx.StaticVariable("LinuxArm64GccTriple", "aarch64-linux-gnu")
+
+	pctx.StaticVariable("LinuxArm64Cflags", strings.Join(linuxArm64Cflags, " "))
+	pctx.StaticVariable("LinuxArm64ClangCflags", strings.Join(linuxArm64ClangCflags, " "))
+	pctx.StaticVariable("LinuxArm64ClangLdflags", strings.Join(linuxArm64ClangLdflags, " "))
+	pctx.StaticVariable("LinuxArm64ClangLldflags", strings.Join(linuxArm64ClangLldflags, " "))
+	pctx.StaticVariable("LinuxArm64ClangCppflags", strings.Join(linuxArm64ClangCppflags, " "))
+
+	pctx.StaticVariable("LinuxBionicArm64GccRoot", "${Arm64GccRoot}")
+	pctx.StaticVariable("LinuxBionicArm64IncludeFlags", bionicHeaders("arm64"))
+	pctx.StaticVariable("LinuxBionicArm64Cflags", strings.Join(linuxBionicArm64Cflags, " "))
+	pctx.StaticVariable("LinuxBionicArm64Ldflags", strings.Join(linuxBionicArm64Ldflags, " "))
+	pctx.StaticVariable("LinuxBionicArm64Lldflags", strings.Join(linuxBionicArm64Lldflags, " "))
+}
+
+type toolchainLinuxArm64 struct {
+	toolchain64Bit
+	toolchainLinux
+}
+
+func (t *toolchainLinuxArm64) Name() string {
+	return "arm64"
+}
+
+func (t *toolchainLinuxArm64) GccRoot() string {
+	return "${config.LinuxArm64GccRoot}"
+}
+
+func (t *toolchainLinuxArm64) GccTriple() string {
+	return "${config.LinuxArm64GccTriple}"
+}
+
+func (t *toolchainLinuxArm64) Cflags() string {
+	return "${config.LinuxCflags} ${config.LinuxArm64Cflags}"
+}
+
+func (t *toolchainLinuxArm64) Ldflags() string {
+	return "${config.LinuxLdflags}"
+}
+
+func (t *toolchainLinuxArm64) ClangTriple() string {
+	return "aarch64-linux-gnu"
+}
+
+func (t *toolchainLinuxArm64) ClangCflags() string {
+	return "${config.LinuxArm64ClangCflags} ${config.LinuxArm64Cflags}"
+}
+
+func (t *toolchainLinuxArm64) ClangCppflags() string {
+	return "${config.LinuxArm64ClangCppflags}"
+}
+
+func (t *toolchainLinuxArm64) ClangLdflags() string {
+	return "${config.LinuxArm64ClangLdflags}"
+}
+
+func (t *toolchainLinuxArm64) ClangLldflags() string {
+	return "${config.LinuxArm64ClangLldflags}"
+}
+
+func (toolchainLinuxArm64) SanitizerRuntimeLibraryArch() string {
+	return "aarch64"
+}
+
+type toolchainLinuxBionicArm64 struct {
+	toolchainLinuxBionic
+}
+
+func (t *toolchainLinuxBionicArm64) Name() string {
+	return "arm64"
+}
+
+func (t *toolchainLinuxBionicArm64) GccRoot() string {
+	return "${config.LinuxBionicArm64GccRoot}"
+}
+
+func (t *toolchainLinuxBionicArm64) GccTriple() string {
+	return "aarch64-linux-android"
+}
+
+func (t *toolchainLinuxBionicArm64) IncludeFlags() string {
+	return "${config.LinuxBionicArm64IncludeFlags}"
+}
+
+func (t *toolchainLinuxBionicArm64) ClangTriple() string {
+	return "aarch64-linux-android"
+}
+
+func (t *toolchainLinuxBionicArm64) ClangCflags() string {
+	return "${config.LinuxBionicArm64Cflags}"
+}
+
+func (t *toolchainLinuxBionicArm64) ClangLdflags() string {
+	return "${config.LinuxBionicArm64Ldflags}"
+}
+
+func (t *toolchainLinuxBionicArm64) ClangLldflags() string {
+	return "${config.LinuxBionicArm64Lldflags}"
+}
+
+func (t *toolchainLinuxBionicArm64) ToolchainClangCflags() string {
+	return "-march=armv8-a -U__ANDROID__ -fno-emulated-tls"
+}
+
+func (t *toolchainLinuxBionicArm64) ToolchainClangLdflags() string {
+	return ""
+}
+
+func (toolchainLinuxBionicArm64) SanitizerRuntimeLibraryArch() string {
+	return "aarch64"
+}
+
+var toolchainLinuxArm64Singleton Toolchain = &toolchainLinuxArm64{}
+var toolchainLinuxBionicArm64Singleton Toolchain = &toolchainLinuxBionicArm64{}
+
+func linuxArm64ToolchainFactory(config android.Config, arch android.Arch) Toolchain {
+	if config.HostArm64GlibcGccRoot() == "" {
+		return nil
+	}
+	return toolchainLinuxArm64Singleton
+}
+
+func linuxBionicArm64ToolchainFactory(arch android.Arch) Toolchain {
+	return toolchainLinuxBionicArm64Singleton
+}
+
+func init() {
+	registerOptionalToolchainFactory(android.Linux, android.Arm64, linuxArm64ToolchainFactory)
+	registerToolchainFactory(android.LinuxBionic, android.Arm64, linuxBionicArm64ToolchainFactory)
+}
@@ -95,7 +95,6 @@ var (
 
 	NdkMaxPrebuiltVersionInt = 27
 
-	ClangDefaultBase         = android.Prefix()
 	ClangDefaultVersion      = "7.0.0"
 	ClangDefaultShortVersion = "7.0.0"
 
@@ -147,7 +146,7 @@ func init() {
 		})
 	pctx.PrefixedExistentPathsForSourcesVariable("CommonNativehelperInclude", "-I", []string{"libnativehelper/include_deprecated"})
 
-	pctx.StaticVariable("ClangDefaultBase", ClangDefaultBase)
+	pctx.HostToolsPrefixVariable("ClangDefaultBase", "")
 	pctx.VariableFunc("ClangBase", func(ctx android.PackageVarContext) string {
 		if override := ctx.Config().Getenv("LLVM_PREBUILTS_BASE"); override != "" {
 			return override
@@ -160,7 +159,7 @@ func init() {
 		}
 		return ClangDefaultVersion
 	})
-	pctx.StaticVariable("ClangPath", android.Prefix())
+	pctx.HostToolsPrefixVariable("ClangPath", "")
 	pctx.StaticVariable("ClangBin", "${ClangPath}/bin")
 
 	pctx.VariableFunc("ClangShortVersion", func(ctx android.PackageVarContext) string {
@@ -171,7 +170,7 @@ func init() {
 	})
 	pctx.StaticVariable("ClangAsanLibDir", "${ClangPath}/lib/clang/${ClangShortVersion}/lib/linux")
 	pctx.StaticVariable("LLVMGoldPlugin", "${ClangPath}/lib/LLVMgold.so")
-	pctx.StaticVariable("RSClangBase", android.Prefix())
+	pctx.HostToolsPrefixVariable("RSClangBase", "")
 	pctx.StaticVariable("RSClangVersion", ClangDefaultVersion)
 	pctx.StaticVariable("RSReleaseVersion", ClangDefaultShortVersion)
 	pctx.StaticVariable("RSLLVMPrebuiltsPath", "${RSClangBase}/bin")
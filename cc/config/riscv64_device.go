@@ -0,0 +1,158 @@
+package config
+
+import (
+	"fmt"
+	"strings"
+
+	"android/soong/android"
+)
+
+var (
+	riscv64Cflags = []string{
+		"-Werror=implicit-function-declaration",
+		"-mno-relax",
+	}
+
+	riscv64ArchVariantCflags = map[string][]string{
+		"rv64gc": []string{
+			"-march=rv64gc",
+		},
+		"rv64gcv": []string{
+			"-march=rv64gcv",
+		},
+	}
+
+	riscv64Ldflags = []string{
+		"-Wl,--hash-style=gnu",
+		"-fuse-ld=lld",
+		"-Wl,-z,max-page-size=4096",
+	}
+
+	riscv64Lldflags = ClangFilterUnknownLldflags(riscv64Ldflags)
+
+	riscv64Cppflags = []string{}
+)
+
+const (
+	riscv64GccVersion = "7.3.0"
+)
+
+func init() {
+	android.RegisterArchVariants(android.Riscv64,
+		"rv64gc",
+		"rv64gcv")
+	android.RegisterArchFeatures(android.Riscv64,
+		"v",
+		"zba",
+		"zbb",
+		"zbs")
+	android.RegisterArchVariantFeatures(android.Riscv64, "rv64gcv",
+		"v")
+
+	pctx.StaticVariable("riscv64GccVersion", riscv64GccVersion)
+	pctx.HostToolsPrefixVariable("Riscv64GccRoot", "")
+	pctx.StaticVariable("Riscv64Cflags", strings.Join(riscv64Cflags, " "))
+	pctx.StaticVariable("Riscv64Ldflags", strings.Join(riscv64Ldflags, " "))
+	pctx.StaticVariable("Riscv64Lldflags", strings.Join(riscv64Lldflags, " "))
+	pctx.StaticVariable("Riscv64Cppflags", strings.Join(riscv64Cppflags, " "))
+	pctx.StaticVariable("Riscv64IncludeFlags", bionicHeaders("riscv"))
+	pctx.StaticVariable("Riscv64ClangCflags", strings.Join(ClangFilterUnknownCflags(riscv64Cflags), " "))
+	pctx.StaticVariable("Riscv64ClangLdflags", strings.Join(ClangFilterUnknownCflags(riscv64Ldflags), " "))
+	pctx.StaticVariable("Riscv64ClangLldflags", strings.Join(ClangFilterUnknownCflags(riscv64Lldflags), " "))
+	pctx.StaticVariable("Riscv64ClangCppflags", strings.Join(ClangFilterUnknownCflags(riscv64Cppflags), " "))
+	pctx.StaticVariable("Riscv64ClangRv64gcCflags", strings.Join(riscv64ArchVariantCflags["rv64gc"], " "))
+	pctx.StaticVariable("Riscv64ClangRv64gcvCflags", strings.Join(riscv64ArchVariantCflags["rv64gcv"], " "))
+}
+
+var (
+	riscv64ClangArchVariantCflagsVar = map[string]string{
+		"":        "${config.Riscv64ClangRv64gcCflags}",
+		"rv64gc":  "${config.Riscv64ClangRv64gcCflags}",
+		"rv64gcv": "${config.Riscv64ClangRv64gcvCflags}",
+	}
+)
+
+type toolchainRiscv64 struct {
+	toolchain64Bit
+
+	toolchainClangCflags string
+}
+
+func (t *toolchainRiscv64) Name() string {
+	return "riscv64"
+}
+
+func (t *toolchainRiscv64) GccRoot() string {
+	return "${config.Riscv64GccRoot}"
+}
+
+func (t *toolchainRiscv64) GccTriple() string {
+	return "riscv64-linux-android"
+}
+
+func (t *toolchainRiscv64) GccVersion() string {
+	return riscv64GccVersion
+}
+
+func (t *toolchainRiscv64) Cflags() string {
+	return "${config.Riscv64Cflags}"
+}
+
+func (t *toolchainRiscv64) Cppflags() string {
+	return "${config.Riscv64Cppflags}"
+}
+
+func (t *toolchainRiscv64) Ldflags() string {
+	return "${config.Riscv64Ldflags}"
+}
+
+func (t *toolchainRiscv64) IncludeFlags() string {
+	return "${config.Riscv64IncludeFlags}"
+}
+
+func (t *toolchainRiscv64) ClangTriple() string {
+	return t.GccTriple()
+}
+
+func (t *toolchainRiscv64) ClangCflags() string {
+	return "${config.Riscv64ClangCflags}"
+}
+
+func (t *toolchainRiscv64) ClangCppflags() string {
+	return "${config.Riscv64ClangCppflags}"
+}
+
+func (t *toolchainRiscv64) ClangLdflags() string {
+	return "${config.Riscv64Ldflags}"
+}
+
+func (t *toolchainRiscv64) ClangLldflags() string {
+	return "${config.Riscv64Lldflags}"
+}
+
+func (t *toolchainRiscv64) ToolchainClangCflags() string {
+	return t.toolchainClangCflags
+}
+
+func (toolchainRiscv64) SanitizerRuntimeLibraryArch() string {
+	return "riscv64"
+}
+
+func (t *toolchainRiscv64) Bionic() bool {
+	return true
+}
+
+func riscv64ToolchainFactory(arch android.Arch) Toolchain {
+	toolchainClangCflags, ok := riscv64ClangArchVariantCflagsVar[arch.ArchVariant]
+	if !ok {
+		panic(fmt.Sprintf("Unknown RISC-V architecture version: %q", arch.ArchVariant))
+	}
+
+	return &toolchainRiscv64{
+		toolchainClangCflags: toolchainClangCflags,
+	}
+}
+
+func init() {
+	registerToolchainFactory(android.Android, android.Riscv64, riscv64ToolchainFactory)
+}
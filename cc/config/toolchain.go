@@ -2,7 +2,6 @@ package config
 
 import (
 	"fmt"
-	"path/filepath"
 
 	"android/soong/android"
 )
@@ -18,6 +17,28 @@ func registerToolchainFactory(os android.OsType, arch android.ArchType, factory
 	toolchainFactories[os][arch] = factory
 }
 
+type optionalToolchainFactory func(config android.Config, arch android.Arch) Toolchain
+
+var optionalToolchainFactories = make(map[android.OsType]map[android.ArchType]optionalToolchainFactory)
+
+// registerOptionalToolchainFactory registers a toolchain that replaces the one registered with
+// registerToolchainFactory when the factory returns non-nil for the current config.
+func registerOptionalToolchainFactory(os android.OsType, arch android.ArchType, factory optionalToolchainFactory) {
+	if optionalToolchainFactories[os] == nil {
+		optionalToolchainFactories[os] = make(map[android.ArchType]optionalToolchainFactory)
+	}
+	optionalToolchainFactories[os][arch] = factory
+}
+
+func FindToolchainForConfig(config android.Config, os android.OsType, arch android.Arch) Toolchain {
+	if factory := optionalToolchainFactories[os][arch.ArchType]; factory != nil {
+		if toolchain := factory(config, arch); toolchain != nil {
+			return toolchain
+		}
+	}
+	return FindToolchain(os, arch)
+}
+
 func FindToolchain(os android.OsType, arch android.Arch) Toolchain {
 	factory := toolchainFactories[os][arch.ArchType]
 	if factory == nil {
@@ -221,7 +242,7 @@ func ToolPath(t Toolchain) string {
 	if p := t.ToolPath(); p != "" {
 		return p
 	}
-	return filepath.Join(android.Prefix(), "bin")
+	return "${config.ClangBin}"
 }
 
 var inList = android.InList
@@ -7,7 +7,7 @@ import (
 )
 
 var (
-	linuxBionicCflags = ClangFilterUnknownCflags([]string{
+	linuxBionicCommonCflags = ClangFilterUnknownCflags([]string{
 		"-fdiagnostics-color",
 		"-Wa,--noexecstack",
 		"-fPIC",
@@ -21,10 +21,12 @@ var (
 		"-funswitch-loops",
 		"-funwind-tables",
 		"-fno-canonical-system-headers",
-		"--gcc-toolchain=${LinuxBionicGccRoot}",
 	})
 
-	linuxBionicLdflags = ClangFilterUnknownCflags([]string{
+	linuxBionicCflags = append(ClangFilterUnknownCflags(linuxBionicCommonCflags),
+		"--gcc-toolchain=${LinuxBionicGccRoot}")
+
+	linuxBionicCommonLdflags = ClangFilterUnknownCflags([]string{
 		"-Wl,-z,noexecstack",
 		"-Wl,-z,relro",
 		"-Wl,-z,now",
@@ -33,9 +35,11 @@ var (
 		"-Wl,--fatal-warnings",
 		"-Wl,--hash-style=gnu",
 		"-Wl,--no-undefined-version",
-		"--gcc-toolchain=${LinuxBionicGccRoot}",
 	})
 
+	linuxBionicLdflags = append(ClangFilterUnknownCflags(linuxBionicCommonLdflags),
+		"--gcc-toolchain=${LinuxBionicGccRoot}")
+
 	linuxBionicLldflags = ClangFilterUnknownLldflags(linuxBionicLdflags)
 )
 
@@ -1,17 +1,15 @@
 package cc
 
 import (
-	"path/filepath"
-
 	"github.com/google/blueprint"
 
 	"android/soong/android"
 )
 
 func init() {
-	pctx.StaticVariable("lexCmd", android.TermuxExecutable("flex"))
-	pctx.StaticVariable("yaccCmd", android.TermuxExecutable("bison"))
-	pctx.StaticVariable("yaccDataDir", filepath.Join(android.Prefix(), "share/bison"))
+	pctx.HostToolVariable("lexCmd", "flex")
+	pctx.HostToolVariable("yaccCmd", "bison")
+	pctx.HostToolsPrefixVariable("yaccDataDir", "share/bison")
 
 	pctx.HostBinToolVariable("aidlCmd", "aidl-cpp")
 }
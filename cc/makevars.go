@@ -52,22 +52,22 @@ func makeStringOfWarningAllowedProjects() string {
 func makeVarsProvider(ctx android.MakeVarsContext) {
 	ctx.Strict("LLVM_RELEASE_VERSION", "${config.ClangShortVersion}")
 	ctx.Strict("LLVM_PREBUILTS_VERSION", "${config.ClangVersion}")
-	ctx.Strict("LLVM_PREBUILTS_BASE", android.Prefix())
+	ctx.Strict("LLVM_PREBUILTS_BASE", ctx.Config().HostToolsPrefix())
 	ctx.Strict("LLVM_PREBUILTS_PATH", "${config.ClangBin}")
-	ctx.Strict("CLANG", android.TermuxExecutable("clang-7"))
-	ctx.Strict("CLANG_CXX", android.TermuxExecutable("clang++"))
-	ctx.Strict("LLVM_AS", android.TermuxExecutable("llvm-as"))
-	ctx.Strict("LLVM_LINK", android.TermuxExecutable("llvm-link"))
-	ctx.Strict("PATH_TO_CLANG_TIDY", android.TermuxExecutable("clang-tidy"))
+	ctx.Strict("CLANG", ctx.Config().HostTool("clang-7"))
+	ctx.Strict("CLANG_CXX", ctx.Config().HostTool("clang++"))
+	ctx.Strict("LLVM_AS", ctx.Config().HostTool("llvm-as"))
+	ctx.Strict("LLVM_LINK", ctx.Config().HostTool("llvm-link"))
+	ctx.Strict("PATH_TO_CLANG_TIDY", ctx.Config().HostTool("clang-tidy"))
 	ctx.StrictSorted("CLANG_CONFIG_UNKNOWN_CFLAGS", strings.Join(config.ClangUnknownCflags, " "))
 
 	ctx.Strict("RS_LLVM_PREBUILTS_VERSION", "${config.RSClangVersion}")
-	ctx.Strict("RS_LLVM_PREBUILTS_BASE", android.Prefix())
+	ctx.Strict("RS_LLVM_PREBUILTS_BASE", ctx.Config().HostToolsPrefix())
 	ctx.Strict("RS_LLVM_PREBUILTS_PATH", "${config.RSLLVMPrebuiltsPath}")
 	ctx.Strict("RS_LLVM_INCLUDES", "${config.RSIncludePath}")
-	ctx.Strict("RS_CLANG", android.TermuxExecutable("clang-7"))
-	ctx.Strict("RS_LLVM_AS", android.TermuxExecutable("llvm-as"))
-	ctx.Strict("RS_LLVM_LINK", android.TermuxExecutable("llvm-link"))
+	ctx.Strict("RS_CLANG", ctx.Config().HostTool("clang-7"))
+	ctx.Strict("RS_LLVM_AS", ctx.Config().HostTool("llvm-as"))
+	ctx.Strict("RS_LLVM_LINK", ctx.Config().HostTool("llvm-link"))
 
 	ctx.Strict("GLOBAL_CFLAGS_NO_OVERRIDE", "${config.NoOverrideGlobalCflags}")
 	ctx.Strict("GLOBAL_CLANG_CFLAGS_NO_OVERRIDE", "${config.ClangExtraNoOverrideCflags}")
@@ -173,7 +173,7 @@ func makeVarsToolchain(ctx android.MakeVarsContext, secondPrefix string, target
 	}
 	makePrefix := secondPrefix + typePrefix
 
-	toolchain := config.FindToolchain(target.Os, target.Arch)
+	toolchain := config.FindToolchainForConfig(ctx.Config(), target.Os, target.Arch)
 
 	var productExtraCflags string
 	var productExtraLdflags string
@@ -225,14 +225,14 @@ func makeVarsToolchain(ctx android.MakeVarsContext, secondPrefix string, target
 		ctx.Strict(makePrefix+"CLANG_SUPPORTED", "")
 	}
 
-	ctx.Strict(makePrefix+"CC", android.TermuxExecutable("gcc-7"))
-	ctx.Strict(makePrefix+"CXX", android.TermuxExecutable("g++-7"))
-	ctx.Strict(makePrefix+"AR", android.TermuxExecutable("llvm-ar"))
-	ctx.Strict(makePrefix+"READELF", android.TermuxExecutable("readelf"))
-	ctx.Strict(makePrefix+"NM", android.TermuxExecutable("nm"))
-	ctx.Strict(makePrefix+"OBJCOPY", android.TermuxExecutable("objcopy"))
-	ctx.Strict(makePrefix+"LD", android.TermuxExecutable("ld"))
-	ctx.Strict(makePrefix+"STRIP", android.TermuxExecutable("strip"))
+	ctx.Strict(makePrefix+"CC", ctx.Config().HostTool("gcc-7"))
+	ctx.Strict(makePrefix+"CXX", ctx.Config().HostTool("g++-7"))
+	ctx.Strict(makePrefix+"AR", ctx.Config().HostTool("llvm-ar"))
+	ctx.Strict(makePrefix+"READELF", ctx.Config().HostTool("readelf"))
+	ctx.Strict(makePrefix+"NM", ctx.Config().HostTool("nm"))
+	ctx.Strict(makePrefix+"OBJCOPY", ctx.Config().HostTool("objcopy"))
+	ctx.Strict(makePrefix+"LD", ctx.Config().HostTool("ld"))
+	ctx.Strict(makePrefix+"STRIP", ctx.Config().HostTool("strip"))
 	ctx.Strict(makePrefix+"GCC_VERSION", toolchain.GccVersion())
 	ctx.Strict(makePrefix+"NDK_GCC_VERSION", toolchain.GccVersion())
 	ctx.Strict(makePrefix+"NDK_TRIPLE", config.NDKTriple(toolchain))
@@ -81,12 +81,13 @@ func normalizeNdkApiLevel(ctx android.BaseContext, apiLevel string,
 
 	minVersion := ctx.Config().MinSupportedSdkVersion()
 	firstArchVersions := map[android.ArchType]int{
-		android.Arm:    minVersion,
-		android.Arm64:  21,
-		android.Mips:   minVersion,
-		android.Mips64: 21,
-		android.X86:    minVersion,
-		android.X86_64: 21,
+		android.Arm:     minVersion,
+		android.Arm64:   21,
+		android.Mips:    minVersion,
+		android.Mips64:  21,
+		android.X86:     minVersion,
+		android.X86_64:  21,
+		android.Riscv64: android.FutureApiLevel,
 	}
 
 	firstArchVersion, ok := firstArchVersions[arch.ArchType]
@@ -94,6 +95,10 @@ func normalizeNdkApiLevel(ctx android.BaseContext, apiLevel string,
 		panic(fmt.Errorf("Arch %q not found in firstArchVersions", arch.ArchType))
 	}
 
+	if firstArchVersion == android.FutureApiLevel {
+		return "current", nil
+	}
+
 	if apiLevel == "minimum" {
 		return strconv.Itoa(firstArchVersion), nil
 	}
@@ -292,7 +297,7 @@ func (stub *stubDecorator) install(ctx ModuleContext, path android.Path) {
 	apiLevel := stub.properties.ApiLevel
 
 	libDir := "lib"
-	if ctx.toolchain().Is64Bit() && arch != "arm64" {
+	if ctx.toolchain().Is64Bit() && arch != "arm64" && arch != "riscv64" {
 		libDir = "lib64"
 	}
 
@@ -17,7 +17,7 @@ func init() {
 func getNdkLibDir(ctx android.ModuleContext, toolchain config.Toolchain, version string) android.SourcePath {
 	suffix := ""
 
-	if toolchain.Is64Bit() && ctx.Arch().ArchType != android.Arm64 {
+	if toolchain.Is64Bit() && ctx.Arch().ArchType != android.Arm64 && ctx.Arch().ArchType != android.Riscv64 {
 		suffix = "64"
 	}
 	return android.PathForSource(ctx, fmt.Sprintf("prebuilts/ndk/current/platforms/android-%s/arch-%s/usr/lib%s",
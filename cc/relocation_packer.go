@@ -7,7 +7,7 @@ import (
 )
 
 func init() {
-	pctx.StaticVariable("relocationPackerCmd", android.TermuxExecutable("relocation_packer"))
+	pctx.HostToolVariable("relocationPackerCmd", "relocation_packer")
 }
 
 var relocationPackerRule = pctx.AndroidStaticRule("packRelocations",
@@ -60,6 +60,10 @@ type testPerSrc interface {
 	setSrc(string, string)
 }
 
+func (test *testBinary) testSuites() []string {
+	return test.Properties.Test_suites
+}
+
 func (test *testBinary) testPerSrc() bool {
 	return Bool(test.Properties.Test_per_src)
 }
@@ -251,6 +255,10 @@ type benchmarkDecorator struct {
 	data       android.Paths
 }
 
+func (benchmark *benchmarkDecorator) testSuites() []string {
+	return benchmark.Properties.Test_suites
+}
+
 func (benchmark *benchmarkDecorator) linkerInit(ctx BaseModuleContext) {
 	runpath := "../../lib"
 	if ctx.toolchain().Is64Bit() {
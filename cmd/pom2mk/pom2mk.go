@@ -309,7 +309,12 @@ func rerunForRegen(filename string) error {
 
 	cmd := os.Args[0] + " " + strings.Join(args, " ")
 
-	output, err := exec.Command(android.TermuxExecutable("bash"), "-c", cmd).Output()
+	hostTools, err := android.NewHostToolResolverFromEnv(os.Getenv)
+	if err != nil {
+		return err
+	}
+
+	output, err := exec.Command(hostTools.Resolve("bash"), "-c", cmd).Output()
 	if exitErr, _ := err.(*exec.ExitError); exitErr != nil {
 		return fmt.Errorf("failed to run %s\n%s", cmd, string(exitErr.Stderr))
 	} else if err != nil {
@@ -18,6 +18,7 @@ var (
 	outputRoot    string
 	keepOutDir    bool
 	depfileOut    string
+	zipOut        string
 )
 
 func init() {
@@ -32,6 +33,8 @@ func init() {
 
 	flag.StringVar(&depfileOut, "depfile-out", "",
 		"file path of the depfile to generate. This value will replace '__SBOX_DEPFILE__' in the command and will be treated as an output but won't be added to __SBOX_OUT_FILES__")
+	flag.StringVar(&zipOut, "zip-out", "",
+		"file path of the zip file to generate. This value will replace '__SBOX_ZIP_OUT__' in the command and will be treated as an output but won't be added to __SBOX_OUT_FILES__")
 
 }
 
@@ -41,7 +44,7 @@ func usageViolation(violation string) {
 	}
 
 	fmt.Fprintf(os.Stderr,
-		"Usage: sbox -c <commandToRun> --sandbox-path <sandboxPath> --output-root <outputRoot> --overwrite [--depfile-out depFile] <outputFile> [<outputFile>...]\n"+
+		"Usage: sbox -c <commandToRun> --sandbox-path <sandboxPath> --output-root <outputRoot> --overwrite [--depfile-out depFile] [--zip-out zipFile] <outputFile> [<outputFile>...]\n"+
 			"\n"+
 			"Deletes <outputRoot>,"+
 			"runs <commandToRun>,"+
@@ -99,7 +102,7 @@ func run() error {
 
 	// the contents of the __SBOX_OUT_FILES__ variable
 	outputsVarEntries := flag.Args()
-	if len(outputsVarEntries) == 0 {
+	if len(outputsVarEntries) == 0 && zipOut == "" {
 		usageViolation("at least one output file must be given")
 	}
 
@@ -144,6 +147,18 @@ func run() error {
 
 	}
 
+	if zipOut != "" {
+		sandboxedZip, err := filepath.Rel(outputRoot, zipOut)
+		if err != nil {
+			return err
+		}
+		allOutputs = append(allOutputs, sandboxedZip)
+		if !strings.Contains(rawCommand, "__SBOX_ZIP_OUT__") {
+			return fmt.Errorf("the --zip-out argument only makes sense if the command contains the text __SBOX_ZIP_OUT__")
+		}
+		rawCommand = strings.Replace(rawCommand, "__SBOX_ZIP_OUT__", filepath.Join(tempDir, sandboxedZip), -1)
+	}
+
 	if err != nil {
 		return fmt.Errorf("Failed to create temp dir: %s", err)
 	}
@@ -12,11 +12,14 @@ import (
 )
 
 var (
-	docFile string
+	docFile              string
+	dumpProductVariables bool
 )
 
 func init() {
 	flag.StringVar(&docFile, "soong_docs", "", "build documentation file to output")
+	flag.BoolVar(&dumpProductVariables, "dump-product-variables", false,
+		"print the effective product variables as JSON and exit")
 }
 
 func newNameResolver(config android.Config) *android.NameResolver {
@@ -35,6 +38,13 @@ func newNameResolver(config android.Config) *android.NameResolver {
 	return android.NewNameResolver(exportFilter)
 }
 
+func moduleListFile() string {
+	if f := flag.Lookup("l"); f != nil {
+		return f.Value.String()
+	}
+	return ""
+}
+
 func main() {
 	flag.Parse()
 
@@ -50,10 +60,27 @@ func main() {
 		os.Exit(1)
 	}
 
+	if dumpProductVariables {
+		data, err := configuration.ProductVariablesJson()
+		if err != nil {
+			fmt.Fprintln(os.Stderr, err)
+			os.Exit(1)
+		}
+		os.Stdout.Write(data)
+		os.Exit(0)
+	}
+
 	if docFile != "" {
 		configuration.SetStopBefore(bootstrap.StopBeforePrepareBuildActions)
 	}
 
+	if errs := ctx.RegisterSoongConfigModuleTypes(configuration, moduleListFile()); len(errs) > 0 {
+		for _, err := range errs {
+			fmt.Fprintln(os.Stderr, err)
+		}
+		os.Exit(1)
+	}
+
 	ctx.SetNameInterface(newNameResolver(configuration))
 
 	ctx.SetAllowMissingDependencies(configuration.AllowMissingDependencies())
@@ -3,10 +3,15 @@ package main
 import (
 	"android/soong/android"
 	"bytes"
+	"encoding/json"
+	"fmt"
 	"html/template"
 	"io/ioutil"
+	"path/filepath"
+	"strings"
 
 	"github.com/google/blueprint/bootstrap"
+	"github.com/google/blueprint/bootstrap/bpdoc"
 )
 
 func writeDocs(ctx *android.Context, filename string) error {
@@ -38,7 +43,103 @@ func writeDocs(ctx *android.Context, filename string) error {
 		return err
 	}
 
-	return nil
+	docs := android.ModuleTypeDocs()
+	addDocText(docs, moduleTypeList)
+
+	base := strings.TrimSuffix(filename, filepath.Ext(filename))
+	if err := writeJsonDocs(base+".json", docs); err != nil {
+		return err
+	}
+	return writeMarkdownDocs(base+".md", docs)
+}
+
+// addDocText copies the doc comments collected by bpdoc onto the module type
+// and property descriptions built from the registered property structs.
+func addDocText(docs []*android.ModuleTypeDoc, moduleTypeList []*bpdoc.ModuleType) {
+	byName := make(map[string]*android.ModuleTypeDoc)
+	for _, doc := range docs {
+		byName[doc.Name] = doc
+	}
+
+	for _, moduleType := range moduleTypeList {
+		doc := byName[moduleType.Name]
+		if doc == nil {
+			continue
+		}
+		doc.Text = string(moduleType.Text)
+		for _, propertyStruct := range moduleType.PropertyStructs {
+			addPropertyText(&android.PropertyDoc{Properties: doc.Properties}, propertyStruct.Properties)
+		}
+	}
+}
+
+func addPropertyText(parent *android.PropertyDoc, properties []bpdoc.Property) {
+	for _, property := range properties {
+		names := append([]string{property.Name}, property.OtherNames...)
+		for _, name := range names {
+			doc := parent.Find(name)
+			if doc == nil {
+				continue
+			}
+			if doc.Text == "" {
+				texts := []string{string(property.Text)}
+				for _, text := range property.OtherTexts {
+					texts = append(texts, string(text))
+				}
+				doc.Text = strings.TrimSpace(strings.Join(texts, "\n"))
+			}
+			addPropertyText(doc, property.Properties)
+		}
+	}
+}
+
+func writeJsonDocs(filename string, docs []*android.ModuleTypeDoc) error {
+	data, err := json.MarshalIndent(docs, "", "  ")
+	if err != nil {
+		return err
+	}
+	return ioutil.WriteFile(filename, append(data, '\n'), 0666)
+}
+
+func writeMarkdownDocs(filename string, docs []*android.ModuleTypeDoc) error {
+	buf := &bytes.Buffer{}
+
+	fmt.Fprintln(buf, "# Build Docs")
+	for _, doc := range docs {
+		fmt.Fprintf(buf, "\n## %s\n\n", doc.Name)
+		if doc.Text != "" {
+			fmt.Fprintf(buf, "%s\n\n", doc.Text)
+		}
+		writeMarkdownProperties(buf, doc.Properties, "")
+	}
+
+	return ioutil.WriteFile(filename, buf.Bytes(), 0666)
+}
+
+func writeMarkdownProperties(buf *bytes.Buffer, properties []*android.PropertyDoc, indent string) {
+	for _, property := range properties {
+		fmt.Fprintf(buf, "%s* `%s` (`%s`)", indent, property.Name, property.Type)
+
+		var flags []string
+		if property.ArchVariant {
+			flags = append(flags, "arch_variant")
+		}
+		if property.Mutated {
+			flags = append(flags, "mutated")
+		}
+		if len(flags) > 0 {
+			fmt.Fprintf(buf, " _%s_", strings.Join(flags, ", "))
+		}
+		if property.Text != "" {
+			fmt.Fprintf(buf, ": %s", strings.Join(strings.Fields(property.Text), " "))
+		}
+		fmt.Fprintln(buf)
+
+		if len(property.Defaults) > 0 {
+			fmt.Fprintf(buf, "%s  * defaults: %s\n", indent, strings.Join(property.Defaults, ", "))
+		}
+		writeMarkdownProperties(buf, property.Properties, indent+"  ")
+	}
 }
 
 const (
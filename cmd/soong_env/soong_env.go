@@ -11,7 +11,8 @@ import (
 func usage() {
 	fmt.Fprintf(os.Stderr, "usage: soong_env env_file\n")
 	fmt.Fprintf(os.Stderr, "exits with success if the environment varibles in env_file match\n")
-	fmt.Fprintf(os.Stderr, "the current environment\n")
+	fmt.Fprintf(os.Stderr, "the current environment, otherwise prints the changed variables\n")
+	fmt.Fprintf(os.Stderr, "and the modules and singletons that read them\n")
 	flag.PrintDefaults()
 	os.Exit(2)
 }
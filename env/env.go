@@ -6,15 +6,19 @@ import (
 	"io/ioutil"
 	"os"
 	"sort"
+	"strings"
 )
 
-type envFileEntry struct{ Key, Value string }
+type envFileEntry struct {
+	Key, Value string
+	Readers    []string `json:",omitempty"`
+}
 type envFileData []envFileEntry
 
-func WriteEnvFile(filename string, envDeps map[string]string) error {
+func WriteEnvFile(filename string, envDeps map[string]string, envReaders map[string][]string) error {
 	contents := make(envFileData, 0, len(envDeps))
 	for key, value := range envDeps {
-		contents = append(contents, envFileEntry{key, value})
+		contents = append(contents, envFileEntry{key, value, envReaders[key]})
 	}
 
 	sort.Sort(contents)
@@ -53,7 +57,11 @@ func StaleEnvFile(filename string) (bool, error) {
 		old := entry.Value
 		cur := os.Getenv(key)
 		if old != cur {
-			changed = append(changed, fmt.Sprintf("%s (%q -> %q)", key, old, cur))
+			s := fmt.Sprintf("%s (%q -> %q)", key, old, cur)
+			if len(entry.Readers) > 0 {
+				s += fmt.Sprintf("\n      read by: %s", strings.Join(entry.Readers, ", "))
+			}
+			changed = append(changed, s)
 		}
 	}
 
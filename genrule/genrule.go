@@ -2,10 +2,11 @@ package genrule
 
 import (
 	"fmt"
+	"sort"
+	"strconv"
 	"strings"
 
 	"github.com/google/blueprint"
-	"github.com/google/blueprint/bootstrap"
 	"github.com/google/blueprint/proptools"
 
 	"android/soong/android"
@@ -24,8 +25,16 @@ var (
 
 func init() {
 	pctx.HostBinToolVariable("sboxCmd", "sbox")
+	pctx.HostBinToolVariable("soongZip", "soong_zip")
+	pctx.HostBinToolVariable("zipSync", "zipsync")
 }
 
+var unzipOutZip = pctx.AndroidStaticRule("unzipOutZip",
+	blueprint.RuleParams{
+		Command:     "$zipSync -d $outDir -l $out $in",
+		CommandDeps: []string{"$zipSync"},
+	}, "outDir")
+
 type SourceFileGenerator interface {
 	GeneratedSourceFiles() android.Paths
 	GeneratedHeaderDirs() android.Paths
@@ -43,7 +52,11 @@ type hostToolDependencyTag struct {
 var hostToolDepTag hostToolDependencyTag
 
 type generatorProperties struct {
-	Cmd                 *string
+	// the command to run. Available variables: $(location), $(location <label>) and
+	// $(locations <label>) where <label> is an entry of tools, tool_files or srcs, $(in),
+	// $(out), $(out[<index>]), $(depfile) and $(genDir)
+	Cmd *string
+
 	Depfile             *bool
 	Tools               []string
 	Tool_files          []string
@@ -63,13 +76,20 @@ type Module struct {
 	outputDeps          android.Paths
 }
 
-type taskFunc func(ctx android.ModuleContext, rawCommand string, srcFiles android.Paths) generateTask
+type taskFunc func(ctx android.ModuleContext, rawCommand string, srcFiles android.Paths) []generateTask
 
 type generateTask struct {
 	in          android.Paths
 	out         android.WritablePaths
 	sandboxOuts []string
 	cmd         string
+
+	// directory the outputs are written to, sbox replaces everything in it when the task runs
+	genDir android.ModuleGenPath
+
+	// zip file to create from the contents of sandboxOutDir after cmd has run, if any
+	zipOut        android.WritablePath
+	sandboxOutDir string
 }
 
 func (g *Module) GeneratedSourceFiles() android.Paths {
@@ -101,12 +121,8 @@ func (g *Module) DepsMutator(ctx android.BottomUpMutatorContext) {
 }
 
 func (g *Module) GenerateAndroidBuildActions(ctx android.ModuleContext) {
-	if len(g.properties.Export_include_dirs) > 0 {
-		for _, dir := range g.properties.Export_include_dirs {
-			g.exportedIncludeDirs = append(g.exportedIncludeDirs, android.PathForModuleGen(ctx, ctx.ModuleDir(), dir))
-		}
-	} else {
-		g.exportedIncludeDirs = append(g.exportedIncludeDirs, android.PathForModuleGen(ctx, ""))
+	for _, dir := range g.properties.Export_include_dirs {
+		g.exportedIncludeDirs = append(g.exportedIncludeDirs, android.PathForModuleGen(ctx, ctx.ModuleDir(), dir))
 	}
 
 	tools := map[string]android.Path{}
@@ -130,9 +146,9 @@ func (g *Module) GenerateAndroidBuildActions(ctx android.ModuleContext) {
 						break
 					}
 					path = t.HostToolPath()
-				} else if t, ok := module.(bootstrap.GoBinaryTool); ok {
-					if s, err := filepath.Rel(android.PathForOutput(ctx).String(), t.InstallPath()); err == nil {
-						path = android.OptionalPathForPath(android.PathForOutput(ctx, s))
+				} else if t, ok := module.(android.BootstrapHostTool); ok {
+					if p, err := android.PathForBootstrapHostTool(ctx, t); err == nil {
+						path = android.OptionalPathForPath(p)
 					} else {
 						ctx.ModuleErrorf("cannot find path for %q: %v", tool, err)
 						break
@@ -162,7 +178,8 @@ func (g *Module) GenerateAndroidBuildActions(ctx android.ModuleContext) {
 		return
 	}
 
-	toolFiles := ctx.ExpandSources(g.properties.Tool_files, nil)
+	locations := make(map[string]android.Paths)
+	toolFiles := expandLocations(ctx, g.properties.Tool_files, locations)
 	for _, tool := range toolFiles {
 		g.deps = append(g.deps, tool)
 		if _, exists := tools[tool.Rel()]; !exists {
@@ -172,12 +189,15 @@ func (g *Module) GenerateAndroidBuildActions(ctx android.ModuleContext) {
 		}
 	}
 
-	referencedDepfile := false
+	for label, tool := range tools {
+		locations[label] = android.Paths{tool}
+	}
+
+	srcFiles := expandLocations(ctx, g.properties.Srcs, locations)
 
-	srcFiles := ctx.ExpandSources(g.properties.Srcs, nil)
-	task := g.taskGenerator(ctx, String(g.properties.Cmd), srcFiles)
+	referencedDepfile := false
 
-	rawCommand, err := android.Expand(task.cmd, func(name string) (string, error) {
+	rawCommand, err := android.Expand(String(g.properties.Cmd), func(name string) (string, error) {
 		switch name {
 		case "location":
 			if len(g.properties.Tools) == 0 && len(toolFiles) == 0 {
@@ -189,10 +209,9 @@ func (g *Module) GenerateAndroidBuildActions(ctx android.ModuleContext) {
 			} else {
 				return tools[toolFiles[0].Rel()].String(), nil
 			}
-		case "in":
-			return "${in}", nil
-		case "out":
-			return "__SBOX_OUT_FILES__", nil
+		case "in", "out", "outDir":
+			// expanded separately for each task by the task generator
+			return "$(" + name + ")", nil
 		case "depfile":
 			referencedDepfile = true
 			if !Bool(g.properties.Depfile) {
@@ -204,11 +223,24 @@ func (g *Module) GenerateAndroidBuildActions(ctx android.ModuleContext) {
 		default:
 			if strings.HasPrefix(name, "location ") {
 				label := strings.TrimSpace(strings.TrimPrefix(name, "location "))
-				if tool, ok := tools[label]; ok {
-					return tool.String(), nil
-				} else {
-					return "", fmt.Errorf("unknown location label %q", label)
+				paths, err := lookupLocation(locations, label)
+				if err != nil {
+					return "", err
+				}
+				if len(paths) != 1 {
+					return "", fmt.Errorf("label %q has %d files, use $(locations %s) to reference all of them",
+						label, len(paths), label)
 				}
+				return paths[0].String(), nil
+			} else if strings.HasPrefix(name, "locations ") {
+				label := strings.TrimSpace(strings.TrimPrefix(name, "locations "))
+				paths, err := lookupLocation(locations, label)
+				if err != nil {
+					return "", err
+				}
+				return strings.Join(paths.Strings(), " "), nil
+			} else if strings.HasPrefix(name, "out[") {
+				return "$(" + name + ")", nil
 			}
 			return "", fmt.Errorf("unknown variable '$(%s)'", name)
 		}
@@ -223,6 +255,11 @@ func (g *Module) GenerateAndroidBuildActions(ctx android.ModuleContext) {
 		return
 	}
 
+	tasks := g.taskGenerator(ctx, rawCommand, srcFiles)
+	if ctx.Failed() {
+		return
+	}
+
 	// tell the sbox command which directory to use as its sandbox root
 	buildDir := android.PathForOutput(ctx).String()
 	sandboxPath := shared.TempDirForOutDir(buildDir)
@@ -233,65 +270,125 @@ func (g *Module) GenerateAndroidBuildActions(ctx android.ModuleContext) {
 	if Bool(g.properties.Depfile) {
 		depfilePlaceholder = "$depfileArgs"
 	}
+	zipOutPlaceholder := ""
+	commandDeps := []string{"$sboxCmd"}
+	for _, task := range tasks {
+		if task.zipOut != nil {
+			zipOutPlaceholder = "$zipOutArgs"
+			commandDeps = append(commandDeps, "$soongZip")
+			break
+		}
+	}
 
-	genDir := android.PathForModuleGen(ctx)
-	// Escape the command for the shell
-	rawCommand = "'" + strings.Replace(rawCommand, "'", `'\''`, -1) + "'"
-	sandboxCommand := fmt.Sprintf("$sboxCmd --sandbox-path %s --output-root %s -c %s %s $allouts",
-		sandboxPath, genDir, rawCommand, depfilePlaceholder)
+	sandboxCommand := fmt.Sprintf("$sboxCmd --sandbox-path %s --output-root $outputRoot -c $cmd %s %s $allouts",
+		sandboxPath, depfilePlaceholder, zipOutPlaceholder)
 
 	ruleParams := blueprint.RuleParams{
 		Command:     sandboxCommand,
-		CommandDeps: []string{"$sboxCmd"},
+		CommandDeps: commandDeps,
 	}
-	args := []string{"allouts"}
+	args := []string{"cmd", "outputRoot", "allouts"}
 	if Bool(g.properties.Depfile) {
 		ruleParams.Deps = blueprint.DepsGCC
 		args = append(args, "depfileArgs")
 	}
+	if zipOutPlaceholder != "" {
+		args = append(args, "zipOutArgs")
+	}
 	g.rule = ctx.Rule(pctx, "generator", ruleParams, args...)
 
-	g.generateSourceFile(ctx, task)
-
+	for _, task := range tasks {
+		g.generateSourceFile(ctx, task)
+	}
 }
 
 func (g *Module) generateSourceFile(ctx android.ModuleContext, task generateTask) {
+	outputs := append(android.WritablePaths{}, task.out...)
+	if task.zipOut != nil {
+		outputs = append(outputs, task.zipOut)
+	}
+
 	desc := "generate"
-	if len(task.out) == 0 {
+	if len(outputs) == 0 {
 		ctx.ModuleErrorf("must have at least one output file")
 		return
 	}
-	if len(task.out) == 1 {
-		desc += " " + task.out[0].Base()
+	if len(outputs) == 1 {
+		desc += " " + outputs[0].Base()
 	}
 
+	cmd := task.cmd
+	if task.zipOut != nil {
+		// collect the unpredictable set of files written to $(outDir) into a single zip file
+		// inside the sandbox, so that only the zip file is declared as an output
+		cmd = fmt.Sprintf("mkdir -p %s && (%s) && $soongZip -o __SBOX_ZIP_OUT__ -C %s -D %s",
+			task.sandboxOutDir, cmd, task.sandboxOutDir, task.sandboxOutDir)
+	}
+	// Escape the command for the shell
+	cmd = "'" + strings.Replace(cmd, "'", `'\''`, -1) + "'"
+
 	var depFile android.ModuleGenPath
 	if Bool(g.properties.Depfile) {
-		depFile = android.PathForModuleGen(ctx, task.out[0].Rel()+".d")
+		depFile = android.PathForModuleGen(ctx, outputs[0].Rel()+".d")
 	}
 
 	params := android.BuildParams{
 		Rule:            g.rule,
 		Description:     "generate",
-		Output:          task.out[0],
-		ImplicitOutputs: task.out[1:],
+		Output:          outputs[0],
+		ImplicitOutputs: outputs[1:],
 		Inputs:          task.in,
 		Implicits:       g.deps,
 		Args: map[string]string{
-			"allouts": strings.Join(task.sandboxOuts, " "),
+			"cmd":        cmd,
+			"outputRoot": task.genDir.String(),
+			"allouts":    strings.Join(task.sandboxOuts, " "),
 		},
 	}
 	if Bool(g.properties.Depfile) {
-		params.Depfile = android.PathForModuleGen(ctx, task.out[0].Rel()+".d")
+		params.Depfile = android.PathForModuleGen(ctx, outputs[0].Rel()+".d")
 		params.Args["depfileArgs"] = "--depfile-out " + depFile.String()
 	}
+	if task.zipOut != nil {
+		params.Args["zipOutArgs"] = "--zip-out " + task.zipOut.String()
+	}
 
 	ctx.Build(pctx, params)
 
-	for _, outputFile := range task.out {
+	for _, outputFile := range outputs {
 		g.outputFiles = append(g.outputFiles, outputFile)
 	}
-	g.outputDeps = append(g.outputDeps, task.out[0])
+	g.outputDeps = append(g.outputDeps, outputs[0])
+
+	if len(g.properties.Export_include_dirs) == 0 {
+		g.exportedIncludeDirs = append(g.exportedIncludeDirs, task.genDir)
+	}
+
+	if task.zipOut != nil {
+		g.unzipOutZip(ctx, task.zipOut)
+	}
+}
+
+// unzipOutZip extracts the zip file created from $(outDir) so that its contents can be used as
+// generated headers, for example by cc modules.
+func (g *Module) unzipOutZip(ctx android.ModuleContext, zipOut android.WritablePath) {
+	contentsDir := android.PathForModuleGen(ctx, zipOut.Rel()+".contents")
+	contentsList := android.PathForModuleGen(ctx, zipOut.Rel()+".contents.list")
+
+	ctx.Build(pctx, android.BuildParams{
+		Rule:        unzipOutZip,
+		Description: "extract " + zipOut.Base(),
+		Output:      contentsList,
+		Input:       zipOut,
+		Args: map[string]string{
+			"outDir": contentsDir.String(),
+		},
+	})
+
+	if len(g.properties.Export_include_dirs) == 0 {
+		g.exportedIncludeDirs = append(g.exportedIncludeDirs, contentsDir)
+	}
+	g.outputDeps = append(g.outputDeps, contentsList)
 }
 
 func generatorFactory(taskGenerator taskFunc, props ...interface{}) *Module {
@@ -305,6 +402,37 @@ func generatorFactory(taskGenerator taskFunc, props ...interface{}) *Module {
 	return module
 }
 
+// expandLocations expands each label separately so that $(location <label>) and
+// $(locations <label>) can refer to the files of a single label, and returns the files of all
+// labels in order.
+func expandLocations(ctx android.ModuleContext, labels []string, locations map[string]android.Paths) android.Paths {
+	var paths android.Paths
+	for _, label := range labels {
+		labelPaths := ctx.ExpandSources([]string{label}, nil)
+		if _, exists := locations[label]; !exists {
+			locations[label] = labelPaths
+		}
+		paths = append(paths, labelPaths...)
+	}
+	return paths
+}
+
+func lookupLocation(locations map[string]android.Paths, label string) (android.Paths, error) {
+	if paths, ok := locations[label]; ok {
+		return paths, nil
+	}
+
+	var labels []string
+	for l := range locations {
+		labels = append(labels, l)
+	}
+	sort.Strings(labels)
+	if len(labels) == 0 {
+		return nil, fmt.Errorf("unknown location label %q, no labels are available from tools, tool_files or srcs", label)
+	}
+	return nil, fmt.Errorf("unknown location label %q, valid labels are: %s", label, strings.Join(labels, ", "))
+}
+
 // replace "out" with "__SBOX_OUT_DIR__/<the value of ${out}>"
 func pathToSandboxOut(path android.Path, genDir android.Path) string {
 	relOut, err := filepath.Rel(genDir.String(), path.String())
@@ -318,49 +446,88 @@ func pathToSandboxOut(path android.Path, genDir android.Path) string {
 func NewGenSrcs() *Module {
 	properties := &genSrcsProperties{}
 
-	taskGenerator := func(ctx android.ModuleContext, rawCommand string, srcFiles android.Paths) generateTask {
-		commands := []string{}
-		outFiles := android.WritablePaths{}
-		genDir := android.PathForModuleGen(ctx)
-		sandboxOuts := []string{}
-		for _, in := range srcFiles {
-			outFile := android.GenPathWithExt(ctx, "", in, String(properties.Output_extension))
-			outFiles = append(outFiles, outFile)
-
-			sandboxOutfile := pathToSandboxOut(outFile, genDir)
-			sandboxOuts = append(sandboxOuts, sandboxOutfile)
-
-			command, err := android.Expand(rawCommand, func(name string) (string, error) {
-				switch name {
-				case "in":
-					return in.String(), nil
-				case "out":
-					return sandboxOutfile, nil
-				default:
-					return "$(" + name + ")", nil
-				}
-			})
-			if err != nil {
-				ctx.PropertyErrorf("cmd", err.Error())
+	taskGenerator := func(ctx android.ModuleContext, rawCommand string, srcFiles android.Paths) []generateTask {
+		shardSize := len(srcFiles)
+		if properties.Shard_size != nil {
+			if *properties.Shard_size <= 0 {
+				ctx.PropertyErrorf("shard_size", "must be greater than 0, got %d", *properties.Shard_size)
+				return nil
 			}
+			shardSize = int(*properties.Shard_size)
+		}
 
-			// escape the command in case for example it contains '#', an odd number of '"', etc
-			command = fmt.Sprintf("bash -c %v", proptools.ShellEscape([]string{command})[0])
-			commands = append(commands, command)
+		// the command is run once per input file by a loop in the shard's command, with $(in) and
+		// $(out) replaced by shell variables that the loop sets for each file
+		command, err := android.Expand(rawCommand, func(name string) (string, error) {
+			switch name {
+			case "in":
+				return "$${in}", nil
+			case "out":
+				return "$${out}", nil
+			default:
+				return "", fmt.Errorf("$(%s) is not supported by gensrcs", name)
+			}
+		})
+		if err != nil {
+			ctx.PropertyErrorf("cmd", "%s", err.Error())
+			return nil
 		}
-		fullCommand := strings.Join(commands, " && ")
 
-		return generateTask{
-			in:          srcFiles,
-			out:         outFiles,
-			sandboxOuts: sandboxOuts,
-			cmd:         fullCommand,
+		if len(srcFiles) == 0 {
+			return []generateTask{genSrcsTask(ctx, command, srcFiles, "", properties)}
+		}
+
+		var tasks []generateTask
+		for start := 0; start < len(srcFiles); start += shardSize {
+			end := start + shardSize
+			if end > len(srcFiles) {
+				end = len(srcFiles)
+			}
+			// each shard writes to its own directory, so that sbox can clear out the outputs of
+			// a previous run of the shard without touching the outputs of the other shards
+			shardDir := ""
+			if properties.Shard_size != nil {
+				shardDir = "shard" + strconv.Itoa(start/shardSize)
+			}
+			tasks = append(tasks, genSrcsTask(ctx, command, srcFiles[start:end], shardDir, properties))
 		}
+		return tasks
 	}
 
 	return generatorFactory(taskGenerator, properties)
 }
 
+func genSrcsTask(ctx android.ModuleContext, command string, srcFiles android.Paths, shardDir string,
+	properties *genSrcsProperties) generateTask {
+
+	outFiles := android.WritablePaths{}
+	genDir := android.PathForModuleGen(ctx, shardDir)
+	sandboxOuts := []string{}
+	files := []string{}
+	for _, in := range srcFiles {
+		outFile := android.GenPathWithExt(ctx, shardDir, in, String(properties.Output_extension))
+		outFiles = append(outFiles, outFile)
+
+		sandboxOutfile := pathToSandboxOut(outFile, genDir)
+		sandboxOuts = append(sandboxOuts, sandboxOutfile)
+
+		files = append(files, in.String(), sandboxOutfile)
+	}
+
+	// escape the command in case for example it contains '#', an odd number of '"', etc, and run
+	// it in a subshell so that each file starts from the same state
+	loop := fmt.Sprintf("cmd=%s; set -- %s; while [ $$# -gt 0 ]; do in=$$1; out=$$2; shift 2; "+
+		"(eval \"$$cmd\") || exit 1; done", proptools.ShellEscape([]string{command})[0], strings.Join(files, " "))
+
+	return generateTask{
+		in:          srcFiles,
+		out:         outFiles,
+		sandboxOuts: sandboxOuts,
+		cmd:         loop,
+		genDir:      genDir,
+	}
+}
+
 func GenSrcsFactory() android.Module {
 	m := NewGenSrcs()
 	android.InitAndroidModule(m)
@@ -369,12 +536,18 @@ func GenSrcsFactory() android.Module {
 
 type genSrcsProperties struct {
 	Output_extension *string
+
+	// maximum number of input files to process in a single sandboxed command. Each shard is a
+	// separate build action that only reruns when one of its own inputs changes, and writes its
+	// outputs into its own subdirectory of $(genDir). Defaults to processing all input files in
+	// one command.
+	Shard_size *int64
 }
 
 func NewGenRule() *Module {
 	properties := &genRuleProperties{}
 
-	taskGenerator := func(ctx android.ModuleContext, rawCommand string, srcFiles android.Paths) generateTask {
+	taskGenerator := func(ctx android.ModuleContext, rawCommand string, srcFiles android.Paths) []generateTask {
 		outs := make(android.WritablePaths, len(properties.Out))
 		sandboxOuts := make([]string, len(properties.Out))
 		genDir := android.PathForModuleGen(ctx)
@@ -382,12 +555,54 @@ func NewGenRule() *Module {
 			outs[i] = android.PathForModuleGen(ctx, out)
 			sandboxOuts[i] = pathToSandboxOut(outs[i], genDir)
 		}
-		return generateTask{
+		task := generateTask{
 			in:          srcFiles,
 			out:         outs,
 			sandboxOuts: sandboxOuts,
-			cmd:         rawCommand,
+			genDir:      genDir,
 		}
+
+		if outZip := String(properties.Out_zip); outZip != "" {
+			if filepath.Ext(outZip) != ".srcjar" {
+				ctx.PropertyErrorf("out_zip", "must end in .srcjar, got %q", outZip)
+				return nil
+			}
+			task.zipOut = android.PathForModuleGen(ctx, outZip)
+			task.sandboxOutDir = pathToSandboxOut(task.zipOut, genDir) + ".dir"
+		}
+
+		command, err := android.Expand(rawCommand, func(name string) (string, error) {
+			switch name {
+			case "in":
+				return strings.Join(srcFiles.Strings(), " "), nil
+			case "out":
+				return "__SBOX_OUT_FILES__", nil
+			case "outDir":
+				if task.zipOut == nil {
+					return "", fmt.Errorf("$(outDir) used without out_zip property")
+				}
+				return task.sandboxOutDir, nil
+			default:
+				if !strings.HasSuffix(name, "]") {
+					return "", fmt.Errorf("unknown variable '$(%s)'", name)
+				}
+				index, err := strconv.Atoi(strings.TrimSpace(name[len("out[") : len(name)-1]))
+				if err != nil {
+					return "", fmt.Errorf("invalid output index in $(%s)", name)
+				}
+				if index < 0 || index >= len(task.sandboxOuts) {
+					return "", fmt.Errorf("$(%s) is out of range, there are %d output files", name, len(task.sandboxOuts))
+				}
+				return task.sandboxOuts[index], nil
+			}
+		})
+		if err != nil {
+			ctx.PropertyErrorf("cmd", "%s", err.Error())
+			return nil
+		}
+		task.cmd = command
+
+		return []generateTask{task}
 	}
 
 	return generatorFactory(taskGenerator, properties)
@@ -402,6 +617,11 @@ func GenRuleFactory() android.Module {
 type genRuleProperties struct {
 	// names of the output files that will be generated
 	Out []string
+
+	// name of a .srcjar file that will be created from the files the command writes into
+	// $(outDir), for tools whose outputs cannot be listed up front. The srcjar can be used as
+	// java srcs, and its extracted contents are exported as an include directory for cc.
+	Out_zip *string
 }
 
 var Bool = proptools.Bool
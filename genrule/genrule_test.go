@@ -0,0 +1,558 @@
+package genrule
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"reflect"
+	"regexp"
+	"strings"
+	"testing"
+
+	"github.com/google/blueprint"
+
+	"android/soong/android"
+)
+
+var buildDir string
+
+func setUp() {
+	var err error
+	buildDir, err = ioutil.TempDir("", "soong_genrule_test")
+	if err != nil {
+		panic(err)
+	}
+}
+
+func tearDown() {
+	os.RemoveAll(buildDir)
+}
+
+func TestMain(m *testing.M) {
+	run := func() int {
+		setUp()
+		defer tearDown()
+
+		return m.Run()
+	}
+
+	os.Exit(run())
+}
+
+func testContext(config android.Config, bp string) *android.TestContext {
+	ctx := android.NewTestArchContext()
+	ctx.RegisterModuleType("filegroup", android.ModuleFactoryAdaptor(android.FileGroupFactory))
+	ctx.RegisterModuleType("genrule", android.ModuleFactoryAdaptor(GenRuleFactory))
+	ctx.RegisterModuleType("gensrcs", android.ModuleFactoryAdaptor(GenSrcsFactory))
+	ctx.RegisterModuleType("tool", android.ModuleFactoryAdaptor(toolFactory))
+	ctx.RegisterModuleType("go_tool", goToolFactory)
+	ctx.RegisterSingletonType("build_graph_lint", android.SingletonFactoryAdaptor(android.BuildGraphLintSingleton))
+	ctx.Register()
+
+	ctx.MockFileSystem(map[string][]byte{
+		"Android.bp": []byte(bp),
+		"tool_file1": nil,
+		"tool_file2": nil,
+		"in1":        nil,
+		"in2":        nil,
+		"in3":        nil,
+		"in1.txt":    nil,
+		"in2.txt":    nil,
+		"in3.txt":    nil,
+	})
+
+	return ctx
+}
+
+func testGenrule(t *testing.T, config android.Config, bp string) *android.TestContext {
+	t.Helper()
+
+	ctx := testContext(config, bp)
+	_, errs := ctx.ParseFileList(".", []string{"Android.bp"})
+	android.FailIfErrored(t, errs)
+	_, errs = ctx.ResolveDependencies(config)
+	android.FailIfErrored(t, errs)
+	_, errs = ctx.PrepareBuildActions(config)
+	android.FailIfErrored(t, errs)
+
+	return ctx
+}
+
+func testGenruleError(t *testing.T, pattern, bp string) {
+	t.Helper()
+
+	config := android.TestArchConfig(buildDir, nil)
+	ctx := testContext(config, bp)
+	_, errs := ctx.ParseFileList(".", []string{"Android.bp"})
+	if len(errs) == 0 {
+		_, errs = ctx.ResolveDependencies(config)
+	}
+	if len(errs) == 0 {
+		_, errs = ctx.PrepareBuildActions(config)
+	}
+	android.FailIfNoMatchingErrors(t, pattern, errs)
+}
+
+func TestGenruleGoToolPassesBuildGraphLint(t *testing.T) {
+	config := android.TestArchConfig(buildDir, map[string]string{
+		"SOONG_BUILD_GRAPH_LINT": "error",
+	})
+
+	ctx := testGenrule(t, config, `
+		go_tool {
+			name: "go_tool",
+		}
+
+		genrule {
+			name: "gen",
+			tools: ["go_tool"],
+			srcs: ["in1"],
+			out: ["out"],
+			cmd: "$(location go_tool) $(in) > $(out)",
+		}
+	`)
+
+	gen := ctx.ModuleForTests("gen", "").Output("out")
+	tool := filepath.Join(buildDir, "host", "linux-x86", "bin", "go_tool")
+	if !android.InList(tool, gen.Implicits.Strings()) {
+		t.Errorf("expected %q in implicits, got %q", tool, gen.Implicits.Strings())
+	}
+}
+
+func TestGenruleCmd(t *testing.T) {
+	testcases := []struct {
+		name string
+		prop string
+
+		expect string
+		err    string
+	}{
+		{
+			name: "$(location) with tool",
+			prop: `
+				tools: ["tool"],
+				out: ["out"],
+				cmd: "$(location) > $(out)",
+			`,
+			expect: "out/tool > __SBOX_OUT_FILES__",
+		},
+		{
+			name: "$(location) with tool file",
+			prop: `
+				tool_files: ["tool_file1"],
+				out: ["out"],
+				cmd: "$(location) > $(out)",
+			`,
+			expect: "tool_file1 > __SBOX_OUT_FILES__",
+		},
+		{
+			name: "$(location <label>) of a tool and a tool file",
+			prop: `
+				tools: ["tool"],
+				tool_files: ["tool_file1"],
+				out: ["out"],
+				cmd: "$(location tool) $(location tool_file1) > $(out)",
+			`,
+			expect: "out/tool tool_file1 > __SBOX_OUT_FILES__",
+		},
+		{
+			name: "$(location <label>) of a src",
+			prop: `
+				tools: ["tool"],
+				srcs: ["in1", "in2"],
+				out: ["out"],
+				cmd: "$(location) --first $(location in2) $(in) > $(out)",
+			`,
+			expect: "out/tool --first in2 in1 in2 > __SBOX_OUT_FILES__",
+		},
+		{
+			name: "$(locations <label>) of a filegroup",
+			prop: `
+				tools: ["tool"],
+				tool_files: [":tool_files"],
+				srcs: [":ins"],
+				out: ["out"],
+				cmd: "$(location tool) $(locations :tool_files) -- $(locations :ins) > $(out)",
+			`,
+			expect: "out/tool tool_file1 tool_file2 -- in1 in2 > __SBOX_OUT_FILES__",
+		},
+		{
+			name: "$(location <label>) of a filegroup with one file",
+			prop: `
+				tools: ["tool"],
+				srcs: [":in3"],
+				out: ["out"],
+				cmd: "$(location tool) $(location :in3) > $(out)",
+			`,
+			expect: "out/tool in3 > __SBOX_OUT_FILES__",
+		},
+		{
+			name: "$(out[<index>])",
+			prop: `
+				tools: ["tool"],
+				out: ["out", "out2"],
+				cmd: "$(location) --a $(out[0]) --b $(out[1])",
+			`,
+			expect: "out/tool --a __SBOX_OUT_DIR__/out --b __SBOX_OUT_DIR__/out2",
+		},
+		{
+			name: "error no location",
+			prop: `
+				out: ["out"],
+				cmd: "$(location) > $(out)",
+			`,
+			err: "at least one `tools` or `tool_files` is required if $(location) is used",
+		},
+		{
+			name: "error $(location <label>) of several files",
+			prop: `
+				tools: ["tool"],
+				srcs: [":ins"],
+				out: ["out"],
+				cmd: "$(location tool) $(location :ins) > $(out)",
+			`,
+			err: `label ":ins" has 2 files, use $(locations :ins) to reference all of them`,
+		},
+		{
+			name: "error unknown label",
+			prop: `
+				tools: ["tool"],
+				srcs: ["in1"],
+				out: ["out"],
+				cmd: "$(location missing) > $(out)",
+			`,
+			err: `unknown location label "missing", valid labels are: in1, tool`,
+		},
+		{
+			name: "error out index out of range",
+			prop: `
+				tools: ["tool"],
+				out: ["out"],
+				cmd: "$(location) > $(out[1])",
+			`,
+			err: `$(out[1]) is out of range, there are 1 output files`,
+		},
+		{
+			name: "error invalid out index",
+			prop: `
+				tools: ["tool"],
+				out: ["out"],
+				cmd: "$(location) > $(out[first])",
+			`,
+			err: `invalid output index in $(out[first])`,
+		},
+	}
+
+	for _, test := range testcases {
+		t.Run(test.name, func(t *testing.T) {
+			bp := `
+				tool {
+					name: "tool",
+				}
+
+				filegroup {
+					name: "tool_files",
+					srcs: ["tool_file1", "tool_file2"],
+				}
+
+				filegroup {
+					name: "ins",
+					srcs: ["in1", "in2"],
+				}
+
+				filegroup {
+					name: "in3",
+					srcs: ["in3"],
+				}
+
+				genrule {
+					name: "gen",
+					` + test.prop + `
+				}
+			`
+
+			if test.err != "" {
+				testGenruleError(t, regexp.QuoteMeta(test.err), bp)
+				return
+			}
+
+			ctx := testGenrule(t, android.TestArchConfig(buildDir, nil), bp)
+			gen := ctx.ModuleForTests("gen", "").Output("out")
+			if expected := "'" + test.expect + "'"; gen.Args["cmd"] != expected {
+				t.Errorf("want %q, got %q", expected, gen.Args["cmd"])
+			}
+		})
+	}
+}
+
+func TestGenruleOutZip(t *testing.T) {
+	ctx := testGenrule(t, android.TestArchConfig(buildDir, nil), `
+		tool {
+			name: "tool",
+		}
+
+		genrule {
+			name: "gen",
+			tools: ["tool"],
+			srcs: ["in1"],
+			out_zip: "out.srcjar",
+			cmd: "$(location) $(in) -o $(outDir)",
+		}
+	`)
+
+	module := ctx.ModuleForTests("gen", "")
+	gen := module.Output("out.srcjar")
+
+	expectedCmd := "'mkdir -p __SBOX_OUT_DIR__/out.srcjar.dir && " +
+		"(out/tool in1 -o __SBOX_OUT_DIR__/out.srcjar.dir) && " +
+		"$soongZip -o __SBOX_ZIP_OUT__ -C __SBOX_OUT_DIR__/out.srcjar.dir -D __SBOX_OUT_DIR__/out.srcjar.dir'"
+	if gen.Args["cmd"] != expectedCmd {
+		t.Errorf("want cmd %q, got %q", expectedCmd, gen.Args["cmd"])
+	}
+
+	if expected := "--zip-out " + gen.Output.String(); gen.Args["zipOutArgs"] != expected {
+		t.Errorf("want zipOutArgs %q, got %q", expected, gen.Args["zipOutArgs"])
+	}
+
+	m := module.Module().(*Module)
+	if got := m.GeneratedSourceFiles().Strings(); len(got) != 1 || got[0] != gen.Output.String() {
+		t.Errorf("want generated source files [%q], got %q", gen.Output.String(), got)
+	}
+
+	unzip := module.Output("out.srcjar.contents.list")
+	if unzip.Input.String() != gen.Output.String() {
+		t.Errorf("want %q to be extracted, got %q", gen.Output.String(), unzip.Input.String())
+	}
+	if !android.InList(unzip.Output.String(), m.GeneratedDeps().Strings()) {
+		t.Errorf("want %q in generated deps, got %q", unzip.Output.String(), m.GeneratedDeps().Strings())
+	}
+}
+
+func TestGenruleOutZipErrors(t *testing.T) {
+	testcases := []struct {
+		name string
+		prop string
+		err  string
+	}{
+		{
+			name: "out_zip without .srcjar extension",
+			prop: `
+				out_zip: "out.zip",
+				cmd: "$(location) -o $(outDir)",
+			`,
+			err: `must end in .srcjar, got "out.zip"`,
+		},
+		{
+			name: "$(outDir) without out_zip",
+			prop: `
+				out: ["out"],
+				cmd: "$(location) -o $(outDir)",
+			`,
+			err: `$(outDir) used without out_zip property`,
+		},
+	}
+
+	for _, test := range testcases {
+		t.Run(test.name, func(t *testing.T) {
+			testGenruleError(t, regexp.QuoteMeta(test.err), `
+				tool {
+					name: "tool",
+				}
+
+				genrule {
+					name: "gen",
+					tools: ["tool"],
+					`+test.prop+`
+				}
+			`)
+		})
+	}
+}
+
+func TestGenSrcsShards(t *testing.T) {
+	type shard struct {
+		out        string
+		outs       []string
+		in         []string
+		outputRoot string
+		files      string
+	}
+
+	testcases := []struct {
+		name   string
+		prop   string
+		shards []shard
+	}{
+		{
+			name: "no shard_size",
+			prop: ``,
+			shards: []shard{
+				{
+					out:        "in1.h",
+					outs:       []string{"in1.h", "in2.h", "in3.h"},
+					in:         []string{"in1.txt", "in2.txt", "in3.txt"},
+					outputRoot: "gen",
+					files: "in1.txt __SBOX_OUT_DIR__/in1.h in2.txt __SBOX_OUT_DIR__/in2.h " +
+						"in3.txt __SBOX_OUT_DIR__/in3.h",
+				},
+			},
+		},
+		{
+			name: "shard_size 2",
+			prop: `shard_size: 2,`,
+			shards: []shard{
+				{
+					out:        "shard0/in1.h",
+					outs:       []string{"shard0/in1.h", "shard0/in2.h"},
+					in:         []string{"in1.txt", "in2.txt"},
+					outputRoot: "gen/shard0",
+					files:      "in1.txt __SBOX_OUT_DIR__/in1.h in2.txt __SBOX_OUT_DIR__/in2.h",
+				},
+				{
+					out:        "shard1/in3.h",
+					outs:       []string{"shard1/in3.h"},
+					in:         []string{"in3.txt"},
+					outputRoot: "gen/shard1",
+					files:      "in3.txt __SBOX_OUT_DIR__/in3.h",
+				},
+			},
+		},
+	}
+
+	for _, test := range testcases {
+		t.Run(test.name, func(t *testing.T) {
+			ctx := testGenrule(t, android.TestArchConfig(buildDir, nil), `
+				tool {
+					name: "tool",
+				}
+
+				gensrcs {
+					name: "gen",
+					tools: ["tool"],
+					srcs: ["in1.txt", "in2.txt", "in3.txt"],
+					output_extension: "h",
+					cmd: "$(location) $(in) > $(out)",
+					`+test.prop+`
+				}
+			`)
+
+			module := ctx.ModuleForTests("gen", "")
+			var outputs []string
+			for _, s := range test.shards {
+				gen := module.Output(s.out)
+
+				outs := []string{gen.Output.Rel()}
+				for _, out := range gen.ImplicitOutputs {
+					outs = append(outs, out.Rel())
+				}
+				if !reflect.DeepEqual(outs, s.outs) {
+					t.Errorf("%s: want outputs %q, got %q", s.out, s.outs, outs)
+				}
+				outputs = append(outputs, outs...)
+
+				if !reflect.DeepEqual(gen.Inputs.Strings(), s.in) {
+					t.Errorf("%s: want inputs %q, got %q", s.out, s.in, gen.Inputs.Strings())
+				}
+
+				if !strings.HasSuffix(gen.Args["outputRoot"], "/"+s.outputRoot) {
+					t.Errorf("%s: want output root ending in %q, got %q", s.out, s.outputRoot, gen.Args["outputRoot"])
+				}
+
+				cmd := gen.Args["cmd"]
+				for _, expected := range []string{"set -- " + s.files + ";", "while [ $$# -gt 0 ]", "out/tool $${in} > $${out}"} {
+					if !strings.Contains(cmd, expected) {
+						t.Errorf("%s: want %q in cmd, got %q", s.out, expected, cmd)
+					}
+				}
+			}
+
+			var generated []string
+			for _, f := range module.Module().(*Module).GeneratedSourceFiles() {
+				generated = append(generated, f.Rel())
+			}
+			if !reflect.DeepEqual(generated, outputs) {
+				t.Errorf("want generated source files %q, got %q", outputs, generated)
+			}
+		})
+	}
+}
+
+func TestGenSrcsErrors(t *testing.T) {
+	testcases := []struct {
+		name string
+		prop string
+		err  string
+	}{
+		{
+			name: "shard_size 0",
+			prop: `
+				shard_size: 0,
+				cmd: "$(location) $(in) > $(out)",
+			`,
+			err: `must be greater than 0, got 0`,
+		},
+		{
+			name: "out index",
+			prop: `
+				cmd: "$(location) $(in) > $(out[0])",
+			`,
+			err: `$(out[0]) is not supported by gensrcs`,
+		},
+	}
+
+	for _, test := range testcases {
+		t.Run(test.name, func(t *testing.T) {
+			testGenruleError(t, regexp.QuoteMeta(test.err), `
+				tool {
+					name: "tool",
+				}
+
+				gensrcs {
+					name: "gen",
+					tools: ["tool"],
+					srcs: ["in1.txt"],
+					`+test.prop+`
+				}
+			`)
+		})
+	}
+}
+
+type testTool struct {
+	android.ModuleBase
+	outputFile android.Path
+}
+
+func toolFactory() android.Module {
+	module := &testTool{}
+	android.InitAndroidArchModule(module, android.HostSupported, android.MultilibFirst)
+	return module
+}
+
+func (t *testTool) DepsMutator(ctx android.BottomUpMutatorContext) {}
+
+func (t *testTool) GenerateAndroidBuildActions(ctx android.ModuleContext) {
+	t.outputFile = android.PathForTesting("out", ctx.ModuleName())
+}
+
+func (t *testTool) HostToolPath() android.OptionalPath {
+	return android.OptionalPathForPath(t.outputFile)
+}
+
+var _ HostToolProvider = (*testTool)(nil)
+
+// goTool stands in for a bootstrap_go_binary module, which is built by blueprint bootstrap
+// rather than by an android module.
+type goTool struct{}
+
+func goToolFactory() (blueprint.Module, []interface{}) {
+	return &goTool{}, nil
+}
+
+func (t *goTool) GenerateBuildActions(ctx blueprint.ModuleContext) {}
+
+func (t *goTool) InstallPath() string {
+	return filepath.Join(buildDir, "host", "linux-x86", "bin", "go_tool")
+}
+
+var _ android.BootstrapHostTool = (*goTool)(nil)
@@ -313,7 +313,9 @@ func AndroidLibraryFactory() android.Module {
 
 	module.androidLibraryProperties.BuildAAR = true
 
+	android.InitDefaultsExclusions(module, &module.Module.excludeProperties)
 	android.InitAndroidArchModule(module, android.DeviceSupported, android.MultilibCommon)
+	android.InitDefaultableModule(module)
 	return module
 }
 
@@ -456,6 +458,10 @@ func (a *AARImport) ImplementationJars() android.Paths {
 	return android.Paths{a.classpathFile}
 }
 
+func (a *AARImport) OutputFiles() android.Paths {
+	return android.Paths{a.classpathFile}
+}
+
 func (a *AARImport) AidlIncludeDirs() android.Paths {
 	return nil
 }
@@ -59,6 +59,12 @@ func (a *AndroidApp) ExportedStaticPackages() android.Paths {
 
 var _ AndroidLibraryDependency = (*AndroidApp)(nil)
 
+func (a *AndroidApp) OutputFiles() android.Paths {
+	return android.Paths{a.outputFile}
+}
+
+var _ android.OutputFileProducer = (*AndroidApp)(nil)
+
 type certificate struct {
 	pem, key android.Path
 }
@@ -181,6 +187,8 @@ func AndroidAppFactory() android.Module {
 		&module.aaptProperties,
 		&module.appProperties)
 
+	android.InitDefaultsExclusions(module, &module.Module.excludeProperties)
 	android.InitAndroidArchModule(module, android.DeviceSupported, android.MultilibCommon)
+	android.InitDefaultableModule(module)
 	return module
 }
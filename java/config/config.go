@@ -61,7 +61,7 @@ func init() {
 	pctx.StaticVariable("JlinkCmd", "${JavaToolchain}/jlink")
 	pctx.StaticVariable("JmodCmd", "${JavaToolchain}/jmod")
 	pctx.StaticVariable("JrtFsJar", "${JavaHome}/lib/jrt-fs.jar")
-	pctx.StaticVariable("Ziptime", android.TermuxExecutable("ziptime"))
+	pctx.HostToolVariable("Ziptime", "ziptime")
 
 	pctx.StaticVariable("GenKotlinBuildFileCmd", "build/soong/scripts/gen-kotlin-build-file.sh")
 
@@ -71,9 +71,7 @@ func init() {
 	pctx.HostBinToolVariable("MergeZipsCmd", "merge_zips")
 	pctx.HostBinToolVariable("Zip2ZipCmd", "zip2zip")
 	pctx.HostBinToolVariable("ZipSyncCmd", "zipsync")
-	pctx.VariableFunc("DxCmd", func(ctx android.PackageVarContext) string {
-		return android.TermuxExecutable("dx")
-	})
+	pctx.HostToolVariable("DxCmd", "dx")
 	pctx.HostBinToolVariable("D8Cmd", "d8")
 	pctx.HostBinToolVariable("R8Cmd", "r8-compat-proguard")
 
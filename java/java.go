@@ -150,6 +150,15 @@ type CompilerProperties struct {
 	Instrument bool `blueprint:"mutated"`
 }
 
+// Properties that remove entries inherited from defaults modules.
+type ExcludeProperties struct {
+	// list of java libraries to remove from libs after applying defaults
+	Exclude_libs []string
+
+	// list of java libraries to remove from static_libs after applying defaults
+	Exclude_static_libs []string
+}
+
 type CompilerDeviceProperties struct {
 	// list of module-specific flags that will be used for dex compiles
 	Dxflags []string `android:"arch_variant"`
@@ -229,9 +238,10 @@ type Module struct {
 	android.ModuleBase
 	android.DefaultableModuleBase
 
-	properties       CompilerProperties
-	protoProperties  android.ProtoProperties
-	deviceProperties CompilerDeviceProperties
+	properties        CompilerProperties
+	protoProperties   android.ProtoProperties
+	deviceProperties  CompilerDeviceProperties
+	excludeProperties ExcludeProperties
 
 	// header jar file suitable for inserting into the bootclasspath/classpath of another compile
 	headerJarFile android.Path
@@ -318,6 +328,17 @@ var (
 	proguardRaiseTag = dependencyTag{name: "proguard-raise"}
 )
 
+func (d dependencyTag) DependencyKind() string {
+	switch d.name {
+	case "staticlib":
+		return "java_static_libs"
+	case "javalib":
+		return "java_libs"
+	default:
+		return strings.Replace(d.name, " ", "_", -1)
+	}
+}
+
 type sdkDep struct {
 	useModule, useFiles, useDefaultLibs, invalidVersion bool
 
@@ -1108,6 +1129,7 @@ func LibraryFactory(installable bool) func() android.Module {
 			&module.Module.deviceProperties,
 			&module.Module.protoProperties)
 
+		android.InitDefaultsExclusions(module, &module.Module.excludeProperties)
 		InitJavaModule(module, android.HostAndDeviceSupported)
 		return module
 	}
@@ -1120,6 +1142,7 @@ func LibraryHostFactory() android.Module {
 		&module.Module.properties,
 		&module.Module.protoProperties)
 
+	android.InitDefaultsExclusions(module, &module.Module.excludeProperties)
 	InitJavaModule(module, android.HostSupported)
 	return module
 }
@@ -1143,6 +1166,10 @@ type Test struct {
 	testProperties testProperties
 }
 
+func (j *Test) TestSuites() []string {
+	return j.testProperties.Test_suites
+}
+
 func (j *Test) DepsMutator(ctx android.BottomUpMutatorContext) {
 	j.deps(ctx)
 	if BoolDefault(j.testProperties.Junit, true) {
@@ -1159,6 +1186,7 @@ func TestFactory() android.Module {
 		&module.Module.protoProperties,
 		&module.testProperties)
 
+	android.InitDefaultsExclusions(module, &module.Module.excludeProperties)
 	InitJavaModule(module, android.HostAndDeviceSupported)
 	android.InitDefaultableModule(module)
 	return module
@@ -1172,6 +1200,7 @@ func TestHostFactory() android.Module {
 		&module.Module.protoProperties,
 		&module.testProperties)
 
+	android.InitDefaultsExclusions(module, &module.Module.excludeProperties)
 	InitJavaModule(module, android.HostSupported)
 	android.InitDefaultableModule(module)
 	return module
@@ -1241,6 +1270,7 @@ func BinaryFactory() android.Module {
 		&module.Module.protoProperties,
 		&module.binaryProperties)
 
+	android.InitDefaultsExclusions(module, &module.Module.excludeProperties)
 	android.InitAndroidArchModule(module, android.HostAndDeviceSupported, android.MultilibCommonFirst)
 	android.InitDefaultableModule(module)
 	return module
@@ -1254,6 +1284,7 @@ func BinaryHostFactory() android.Module {
 		&module.Module.protoProperties,
 		&module.binaryProperties)
 
+	android.InitDefaultsExclusions(module, &module.Module.excludeProperties)
 	android.InitAndroidArchModule(module, android.HostSupported, android.MultilibCommonFirst)
 	android.InitDefaultableModule(module)
 	return module
@@ -1318,6 +1349,12 @@ func (j *Import) AidlIncludeDirs() android.Paths {
 	return nil
 }
 
+func (j *Import) OutputFiles() android.Paths {
+	return android.Paths{j.combinedClasspathFile}
+}
+
+var _ android.OutputFileProducer = (*Import)(nil)
+
 var _ android.PrebuiltInterface = (*Import)(nil)
 
 func ImportFactory() android.Module {
@@ -1366,6 +1403,7 @@ func DefaultsFactory(props ...interface{}) android.Module {
 		&CompilerProperties{},
 		&CompilerDeviceProperties{},
 		&android.ProtoProperties{},
+		&ExcludeProperties{},
 	)
 
 	android.InitDefaultsModule(module)
@@ -207,6 +207,8 @@ func (module *sdkLibrary) implPath() string {
 		partition = "odm"
 	} else if module.ProductSpecific() {
 		partition = "product"
+	} else if module.SystemExtSpecific() {
+		partition = "system_ext"
 	}
 	return "/" + partition + "/framework/" + module.implName() + ".jar"
 }
@@ -266,13 +268,14 @@ func (module *sdkLibrary) apiFilePath(apiLevel string, apiScope apiScope) string
 // Creates a static java library that has API stubs
 func (module *sdkLibrary) createStubsLibrary(mctx android.TopDownMutatorContext, apiScope apiScope) {
 	props := struct {
-		Name              *string
-		Srcs              []string
-		Sdk_version       *string
-		Soc_specific      *bool
-		Device_specific   *bool
-		Product_specific  *bool
-		Product_variables struct {
+		Name                *string
+		Srcs                []string
+		Sdk_version         *string
+		Soc_specific        *bool
+		Device_specific     *bool
+		Product_specific    *bool
+		System_ext_specific *bool
+		Product_variables   struct {
 			Unbundled_build struct {
 				Enabled *bool
 			}
@@ -296,6 +299,8 @@ func (module *sdkLibrary) createStubsLibrary(mctx android.TopDownMutatorContext,
 		props.Device_specific = proptools.BoolPtr(true)
 	} else if module.ProductSpecific() {
 		props.Product_specific = proptools.BoolPtr(true)
+	} else if module.SystemExtSpecific() {
+		props.System_ext_specific = proptools.BoolPtr(true)
 	}
 
 	mctx.CreateModule(android.ModuleFactoryAdaptor(LibraryFactory(false)), &props)
@@ -382,14 +387,15 @@ func (module *sdkLibrary) createDocs(mctx android.TopDownMutatorContext, apiScop
 // Creates the runtime library. This is not directly linkable from other modules.
 func (module *sdkLibrary) createImplLibrary(mctx android.TopDownMutatorContext) {
 	props := struct {
-		Name             *string
-		Srcs             []string
-		Libs             []string
-		Static_libs      []string
-		Soc_specific     *bool
-		Device_specific  *bool
-		Product_specific *bool
-		Required         []string
+		Name                *string
+		Srcs                []string
+		Libs                []string
+		Static_libs         []string
+		Soc_specific        *bool
+		Device_specific     *bool
+		Product_specific    *bool
+		System_ext_specific *bool
+		Required            []string
 	}{}
 
 	props.Name = proptools.StringPtr(module.implName())
@@ -405,6 +411,8 @@ func (module *sdkLibrary) createImplLibrary(mctx android.TopDownMutatorContext)
 		props.Device_specific = proptools.BoolPtr(true)
 	} else if module.ProductSpecific() {
 		props.Product_specific = proptools.BoolPtr(true)
+	} else if module.SystemExtSpecific() {
+		props.System_ext_specific = proptools.BoolPtr(true)
 	}
 
 	mctx.CreateModule(android.ModuleFactoryAdaptor(LibraryFactory(true)), &props, &module.deviceProperties)
@@ -450,12 +458,13 @@ func (module *sdkLibrary) createXmlFile(mctx android.TopDownMutatorContext) {
 	// creates a prebuilt_etc module to actually place the xml file under
 	// <partition>/etc/permissions
 	etcProps := struct {
-		Name             *string
-		Src              *string
-		Sub_dir          *string
-		Soc_specific     *bool
-		Device_specific  *bool
-		Product_specific *bool
+		Name                *string
+		Src                 *string
+		Sub_dir             *string
+		Soc_specific        *bool
+		Device_specific     *bool
+		Product_specific    *bool
+		System_ext_specific *bool
 	}{}
 	etcProps.Name = proptools.StringPtr(module.xmlFileName())
 	etcProps.Src = proptools.StringPtr(":" + module.xmlFileName() + "-gen")
@@ -466,6 +475,8 @@ func (module *sdkLibrary) createXmlFile(mctx android.TopDownMutatorContext) {
 		etcProps.Device_specific = proptools.BoolPtr(true)
 	} else if module.ProductSpecific() {
 		etcProps.Product_specific = proptools.BoolPtr(true)
+	} else if module.SystemExtSpecific() {
+		etcProps.System_ext_specific = proptools.BoolPtr(true)
 	}
 	mctx.CreateModule(android.ModuleFactoryAdaptor(android.PrebuiltEtcFactory), &etcProps)
 }
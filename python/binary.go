@@ -60,6 +60,10 @@ func PythonBinaryHostFactory() android.Module {
 	return module.Init()
 }
 
+func (binary *binaryDecorator) testSuites() []string {
+	return binary.binaryProperties.Test_suites
+}
+
 func (binary *binaryDecorator) bootstrapperProps() []interface{} {
 	return []interface{}{&binary.binaryProperties}
 }
@@ -29,6 +29,7 @@ func DefaultsFactory(props ...interface{}) android.Module {
 	module.AddProperties(props...)
 	module.AddProperties(
 		&BaseProperties{},
+		&ExcludeProperties{},
 	)
 
 	android.InitDefaultsModule(module)
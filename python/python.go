@@ -88,6 +88,12 @@ type BaseProperties struct {
 	Actual_version string `blueprint:"mutated"`
 }
 
+// properties that remove entries inherited from python_defaults modules.
+type ExcludeProperties struct {
+	// list of the Python libraries to remove from libs after applying defaults.
+	Exclude_libs []string
+}
+
 type pathMapping struct {
 	dest string
 	src  android.Path
@@ -97,8 +103,9 @@ type Module struct {
 	android.ModuleBase
 	android.DefaultableModuleBase
 
-	properties      BaseProperties
-	protoProperties android.ProtoProperties
+	properties        BaseProperties
+	protoProperties   android.ProtoProperties
+	excludeProperties ExcludeProperties
 
 	// initialize before calling Init
 	hod      android.HostOrDeviceSupported
@@ -178,6 +185,7 @@ func (p *Module) Init() android.Module {
 		p.AddProperties(p.bootstrapper.bootstrapperProps()...)
 	}
 
+	android.InitDefaultsExclusions(p, &p.excludeProperties)
 	android.InitAndroidArchModule(p, p.hod, p.multilib)
 	android.InitDefaultableModule(p)
 
@@ -235,6 +243,22 @@ func (p *Module) HostToolPath() android.OptionalPath {
 	return android.OptionalPathForPath(p.installer.(*binaryDecorator).path)
 }
 
+func (p *Module) OutputFiles() android.Paths {
+	if p.installSource.Valid() {
+		return android.Paths{p.installSource.Path()}
+	}
+	return nil
+}
+
+func (p *Module) TestSuites() []string {
+	if binary, ok := p.bootstrapper.(interface {
+		testSuites() []string
+	}); ok {
+		return binary.testSuites()
+	}
+	return nil
+}
+
 func (p *Module) isEmbeddedLauncherEnabled(actual_version string) bool {
 	switch actual_version {
 	case pyVersion2:
@@ -35,6 +35,8 @@ type configImpl struct {
 	targetDeviceDir string
 
 	brokenDupRules bool
+
+	hostTools *android.HostToolResolver
 }
 
 const srcDirFileCheck = "build/soong/root.bp"
@@ -120,7 +122,16 @@ func NewConfig(ctx Context, args ...string) Config {
 		log.Fatalln("Directory names containing spaces are not supported")
 	}
 
-	java8Home := filepath.Join(android.Prefix(), "lib/jvm/openjdk-9")
+	if hostTools, err := android.NewHostToolResolverFromEnv(func(key string) string {
+		value, _ := ret.environ.Get(key)
+		return value
+	}); err != nil {
+		ctx.Fatalln("Failed to configure host tools:", err)
+	} else {
+		ret.hostTools = hostTools
+	}
+
+	java8Home := filepath.Join(ret.hostTools.Prefix(), "lib/jvm/openjdk-9")
 	java9Home := java8Home
 	javaHome := func() string {
 		return java8Home
@@ -246,6 +257,8 @@ func (c *configImpl) Tapas(ctx Context, apps []string, arch, variant string) {
 		product = "aosp_x86"
 	case "x86_64":
 		product = "aosp_x86_64"
+	case "riscv64":
+		product = "aosp_riscv64"
 	default:
 		ctx.Fatalf("Invalid architecture: %q", arch)
 	}
@@ -447,7 +460,7 @@ func (c *configImpl) HostPrebuiltTag() string {
 }
 
 func (c *configImpl) PrebuiltBuildTool(name string) string {
-	return android.TermuxExecutable(name)
+	return c.hostTools.Resolve(name)
 }
 
 func (c *configImpl) SetBuildBrokenDupRules(val bool) {
@@ -82,6 +82,14 @@ func (c ContextImpl) ImportNinjaLog(filename string, startOffset time.Time) {
 	}
 }
 
+// ImportSoongMetrics imports the analysis phases from soong_metrics.json into
+// the tracer.
+func (c ContextImpl) ImportSoongMetrics(filename string, startOffset time.Time) {
+	if c.Tracer != nil {
+		c.Tracer.ImportSoongMetrics(c.Thread, filename, startOffset)
+	}
+}
+
 func (c ContextImpl) IsTerminal() bool {
 	if term, ok := os.LookupEnv("TERM"); ok {
 		return term != "dumb" && isTerminal(c.Stdout()) && isTerminal(c.Stderr())
@@ -49,6 +49,7 @@ func NewSourceFinder(ctx Context, config Config) (f *finder.Finder) {
 			"Blueprints",
 			"CleanSpec.mk",
 			"TEST_MAPPING",
+			"neverallow.json",
 		},
 	}
 	dumpDir := config.FileListDir()
@@ -94,6 +95,12 @@ func FindSources(ctx Context, config Config, f *finder.Finder) {
 		ctx.Fatalf("Could not find modules: %v", err)
 	}
 
+	neverallows := f.FindNamedAt(".", "neverallow.json")
+	err = dumpListToFile(neverallows, filepath.Join(dumpDir, "neverallow.json.list"))
+	if err != nil {
+		ctx.Fatalf("Could not export neverallow rule list: %v", err)
+	}
+
 	androidBps := f.FindNamedAt(".", "Android.bp")
 	androidBps = append(androidBps, f.FindNamedAt("build/blueprint", "Blueprints")...)
 	if len(androidBps) == 0 {
@@ -85,6 +85,7 @@ func runSoong(ctx Context, config Config) {
 		if config.IsVerbose() {
 			cmd.Args = append(cmd.Args, "-v")
 		}
+		cmd.Environment.Set("SOONG_NEVERALLOW_LIST_FILE", filepath.Join(config.FileListDir(), "neverallow.json.list"))
 		cmd.Sandbox = soongSandbox
 		cmd.Stdin = ctx.Stdin()
 		cmd.Stdout = ctx.Stdout()
@@ -95,5 +96,8 @@ func runSoong(ctx Context, config Config) {
 	}
 
 	ninja("minibootstrap", ".minibootstrap/build.ninja")
+
+	bootstrapStart := time.Now()
 	ninja("bootstrap", ".bootstrap/build.ninja")
+	ctx.ImportSoongMetrics(filepath.Join(config.SoongOutDir(), "soong_metrics.json"), bootstrapStart)
 }
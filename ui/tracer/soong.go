@@ -0,0 +1,72 @@
+package tracer
+
+import (
+	"encoding/json"
+	"io/ioutil"
+	"os"
+	"time"
+)
+
+type soongPhase struct {
+	Name    string `json:"name"`
+	BeginUs uint64 `json:"begin_us"`
+	EndUs   uint64 `json:"end_us"`
+	TotalUs uint64 `json:"total_us"`
+	Calls   int    `json:"calls"`
+}
+
+type soongPhaseArgs struct {
+	TotalUs uint64 `json:"total_us"`
+	Calls   int    `json:"calls"`
+}
+
+// ImportSoongMetrics reads the soong_metrics.json file written by soong_build
+// and writes each analysis phase (mutator passes, build action generation, singletons)
+// out to the trace as a Complete Event.
+//
+// startOffset is when soong_build may have started running, and is used to
+// skip importing a metrics file left over from a previous build.
+func (t *tracerImpl) ImportSoongMetrics(thread Thread, filename string, startOffset time.Time) {
+	t.Begin("soong metrics import", thread)
+	defer t.End(thread)
+
+	if stat, err := os.Stat(filename); err != nil {
+		t.log.Verboseln("Missing soong metrics:", err)
+		return
+	} else if stat.ModTime().Before(startOffset) {
+		t.log.Verboseln("Soong metrics not modified, not importing any phases.")
+		return
+	}
+
+	data, err := ioutil.ReadFile(filename)
+	if err != nil {
+		t.log.Println("Error reading soong metrics:", err)
+		return
+	}
+
+	var metrics struct {
+		Phases []soongPhase `json:"phases"`
+	}
+	if err := json.Unmarshal(data, &metrics); err != nil {
+		t.log.Println("Unable to parse soong metrics:", err)
+		return
+	}
+
+	for _, phase := range metrics.Phases {
+		if phase.EndUs < phase.BeginUs {
+			continue
+		}
+		t.writeEvent(&viewerEvent{
+			Name:  phase.Name,
+			Phase: "X",
+			Time:  phase.BeginUs,
+			Dur:   phase.EndUs - phase.BeginUs,
+			Pid:   0,
+			Tid:   uint64(thread),
+			Arg: &soongPhaseArgs{
+				TotalUs: phase.TotalUs,
+				Calls:   phase.Calls,
+			},
+		})
+	}
+}
@@ -28,6 +28,7 @@ type Tracer interface {
 
 	ImportMicrofactoryLog(filename string)
 	ImportNinjaLog(thread Thread, filename string, startOffset time.Time)
+	ImportSoongMetrics(thread Thread, filename string, startOffset time.Time)
 
 	NewThread(name string) Thread
 }